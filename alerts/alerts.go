@@ -0,0 +1,142 @@
+// Package alerts emits inventory events such as low-stock notifications to
+// a configurable sink.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventLowStock is the type of the event emitted when a product's stock first
+// falls below its reorder threshold.
+const EventLowStock = "product.low_stock"
+
+// LowStockEvent describes a product that has crossed below its reorder threshold.
+type LowStockEvent struct {
+	Type             string `json:"type"`
+	ProductID        int64  `json:"product_id"`
+	Name             string `json:"name"`
+	Stock            int64  `json:"stock"`
+	ReorderThreshold int64  `json:"reorder_threshold"`
+	OccurredAt       string `json:"occurred_at"`
+}
+
+// Sink receives emitted events.
+type Sink interface {
+	Emit(ctx context.Context, event LowStockEvent) error
+}
+
+// LogSink writes events to a logger.
+type LogSink struct {
+	Logger *log.Logger
+}
+
+func (s LogSink) Emit(ctx context.Context, event LowStockEvent) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("low stock: product %d (%s) has %d left, reorder threshold %d",
+		event.ProductID, event.Name, event.Stock, event.ReorderThreshold)
+	return nil
+}
+
+// WebhookSink POSTs events as JSON to a URL.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookSink returns a WebhookSink with a bounded request timeout.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (s *WebhookSink) Emit(ctx context.Context, event LowStockEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+var (
+	mu   sync.RWMutex
+	sink Sink = LogSink{}
+
+	// inFlight counts events Dispatch is still emitting.
+	inFlight sync.WaitGroup
+)
+
+// SetSink replaces the sink used by Dispatch.
+func SetSink(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sink = s
+}
+
+// Dispatch emits the event to the configured sink in the background so a slow
+// webhook never holds up the request that triggered it. Failures are logged,
+// and Drain waits for events still being emitted.
+func Dispatch(event LowStockEvent) {
+	mu.RLock()
+	s := sink
+	mu.RUnlock()
+
+	if event.Type == "" {
+		event.Type = EventLowStock
+	}
+	if event.OccurredAt == "" {
+		event.OccurredAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	inFlight.Add(1)
+	go func() {
+		defer inFlight.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.Emit(ctx, event); err != nil {
+			log.Println("Failed to emit low stock alert for product ID:", event.ProductID, err)
+		}
+	}()
+}
+
+// Drain waits until every dispatched event has been emitted, or returns the
+// context's error if it ends first. It is meant for shutdown, once nothing
+// calls Dispatch any more.
+func Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
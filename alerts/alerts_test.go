@@ -0,0 +1,127 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink records the events it is given. While block is open, Emit waits
+// for it to close.
+type fakeSink struct {
+	mu     sync.Mutex
+	events []LowStockEvent
+	block  chan struct{}
+}
+
+func (s *fakeSink) Emit(ctx context.Context, event LowStockEvent) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *fakeSink) Events() []LowStockEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]LowStockEvent(nil), s.events...)
+}
+
+func useSink(t *testing.T, s Sink) {
+	SetSink(s)
+	t.Cleanup(func() { SetSink(LogSink{}) })
+}
+
+func TestDispatch(t *testing.T) {
+	sink := &fakeSink{}
+	useSink(t, sink)
+
+	Dispatch(LowStockEvent{ProductID: 1, Name: "Kopi", Stock: 2, ReorderThreshold: 5})
+	Dispatch(LowStockEvent{Type: "custom", ProductID: 2, OccurredAt: "2024-05-01T08:30:00Z"})
+	if err := Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := sink.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	byProduct := map[int64]LowStockEvent{}
+	for _, event := range events {
+		byProduct[event.ProductID] = event
+	}
+
+	first := byProduct[1]
+	if first.Type != EventLowStock || first.Name != "Kopi" || first.Stock != 2 || first.ReorderThreshold != 5 {
+		t.Errorf("got %+v", first)
+	}
+	if _, err := time.Parse(time.RFC3339, first.OccurredAt); err != nil {
+		t.Errorf("OccurredAt %q is not RFC 3339", first.OccurredAt)
+	}
+	if second := byProduct[2]; second.Type != "custom" || second.OccurredAt != "2024-05-01T08:30:00Z" {
+		t.Errorf("set fields were replaced: %+v", second)
+	}
+}
+
+// TestDrain checks that Drain waits for an event still being emitted, and
+// gives up when its context ends first.
+func TestDrain(t *testing.T) {
+	sink := &fakeSink{block: make(chan struct{})}
+	useSink(t, sink)
+
+	Dispatch(LowStockEvent{ProductID: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("draining a blocked sink: got %v, want DeadlineExceeded", err)
+	}
+	if len(sink.Events()) != 0 {
+		t.Fatal("event emitted while the sink was blocked")
+	}
+
+	close(sink.block)
+	if err := Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.Events()) != 1 {
+		t.Errorf("got %d events after draining, want 1", len(sink.Events()))
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var got LowStockEvent
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+	event := LowStockEvent{Type: EventLowStock, ProductID: 1, Name: "Kopi", Stock: 2, ReorderThreshold: 5, OccurredAt: "2024-05-01T08:30:00Z"}
+	if err := sink.Emit(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if got != event {
+		t.Errorf("webhook got %+v, want %+v", got, event)
+	}
+
+	status = http.StatusBadGateway
+	if err := sink.Emit(context.Background(), event); err == nil {
+		t.Error("no error for a 502 from the webhook")
+	}
+}
@@ -0,0 +1,64 @@
+// Package apiversion selects the version of the response format a request
+// gets. Clients ask for a version with the API-Version header; clients that
+// do not ask get version 1, the format the API has always used.
+package apiversion
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Header is the request header naming the version a client wants. Responses
+// carry it too, naming the version they use.
+const Header = "API-Version"
+
+// Response format versions.
+const (
+	// V1 shows order lines as the product's fields with the quantity mixed
+	// in and leaves the version out of the envelope. It is kept for
+	// existing clients.
+	V1 = 1
+	// V2 shows order lines with the product, unit price and line total, and
+	// names the version in the envelope.
+	V2 = 2
+
+	// Default is the version of requests that do not ask for one.
+	Default = V1
+	// Latest is the newest version.
+	Latest = V2
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying version.
+func NewContext(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, contextKey{}, version)
+}
+
+// FromContext returns the version stored in ctx, or Default.
+func FromContext(ctx context.Context) int {
+	if version, ok := ctx.Value(contextKey{}).(int); ok {
+		return version
+	}
+	return Default
+}
+
+// Middleware stores the version a request asks for in its context and names
+// it in the response. Requests asking for an unknown version get 400.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := Default
+		if value := r.Header.Get(Header); value != "" {
+			v, err := strconv.Atoi(value)
+			if err != nil || v < V1 || v > Latest {
+				http.Error(w, "Unsupported API version", http.StatusBadRequest)
+				return
+			}
+			version = v
+		}
+
+		w.Header().Set(Header, strconv.Itoa(version))
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), version)))
+	})
+}
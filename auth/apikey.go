@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+// APIKeyHeader is the request header carrying an API key.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPrefix marks strings issued as API keys by this service.
+const apiKeyPrefix = "pno_"
+
+// ErrInvalidAPIKey is returned by an APIKeyStore for unknown or revoked keys.
+var ErrInvalidAPIKey = errors.New("auth: invalid API key")
+
+// APIKeyStore resolves API keys to principals. Implementations receive the
+// hash of the presented key, never the key itself.
+type APIKeyStore interface {
+	LookupAPIKey(hash string) (*Principal, error)
+}
+
+// GenerateAPIKey returns a new random API key and the short prefix used to
+// identify it in listings.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:len(apiKeyPrefix)+8], nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash under which a key is stored.
+// Keys carry 256 bits of entropy, so a fast unsalted hash is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
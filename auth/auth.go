@@ -0,0 +1,87 @@
+// Package auth authenticates API callers and carries their identity through
+// the request context.
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Principal is the authenticated caller of a request. CustomerID links a
+// caller with the customer role to their customer record; it is zero for
+// other callers. Machine clients authenticated by API key have APIKeyID set
+// and are limited to their Scopes instead of holding roles.
+type Principal struct {
+	Subject    string
+	Roles      []string
+	CustomerID int64
+	APIKeyID   int64
+	Scopes     []string
+}
+
+// IsAPIKey reports whether the principal was authenticated by API key.
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != 0
+}
+
+// HasScope reports whether the principal's API key grants scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Anonymous is the principal of a request to a public route made without a token.
+var Anonymous = &Principal{}
+
+// IsAnonymous reports whether the principal made the request without a token.
+func (p *Principal) IsAnonymous() bool {
+	return p.Subject == ""
+}
+
+// HasRole reports whether the principal holds role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// ClientKey identifies the caller of a request for per-client bookkeeping:
+// by API key, then by token subject, and otherwise by remote IP.
+func ClientKey(r *http.Request) string {
+	if p, ok := FromContext(r.Context()); ok {
+		if p.IsAPIKey() {
+			return "key:" + strconv.FormatInt(p.APIKeyID, 10)
+		}
+		if !p.IsAnonymous() {
+			return "user:" + p.Subject
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
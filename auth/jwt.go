@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNoKeys is returned by NewValidator when no signing key is configured.
+var ErrNoKeys = errors.New("auth: no HS256 secret or RS256 public key configured")
+
+// Config holds the keys and expected claims used to validate tokens.
+type Config struct {
+	// HS256Secret verifies HS256 tokens. Empty disables HS256.
+	HS256Secret []byte
+	// RS256Keys verify RS256 tokens, keyed by the token's kid header. A key
+	// stored under "" is used for tokens without a kid.
+	RS256Keys map[string]*rsa.PublicKey
+	// Issuer and Audience, when set, must match the token's iss and aud claims.
+	Issuer   string
+	Audience string
+}
+
+// Validator validates bearer tokens and turns them into principals.
+type Validator struct {
+	cfg     Config
+	methods []string
+}
+
+type claims struct {
+	Roles      []string `json:"roles"`
+	CustomerID int64    `json:"customer_id"`
+	jwt.RegisteredClaims
+}
+
+// NewValidator returns a Validator for cfg. At least one key must be configured.
+func NewValidator(cfg Config) (*Validator, error) {
+	var methods []string
+	if len(cfg.HS256Secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(cfg.RS256Keys) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, ErrNoKeys
+	}
+	return &Validator{cfg: cfg, methods: methods}, nil
+}
+
+// Validate checks a token's signature, expiry, issuer and audience and
+// returns the principal it identifies.
+func (v *Validator) Validate(tokenString string) (*Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+	}
+	if v.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+
+	var c claims
+	_, err := jwt.ParseWithClaims(tokenString, &c, v.key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Subject == "" {
+		return nil, errors.New("auth: token has no subject")
+	}
+
+	return &Principal{Subject: c.Subject, Roles: c.Roles, CustomerID: c.CustomerID}, nil
+}
+
+func (v *Validator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.cfg.HS256Secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.cfg.RS256Keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("auth: unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("auth: unexpected signing method %s", token.Method.Alg())
+}
+
+// LoadRSAPublicKeyPEM reads a PEM encoded RSA public key from a file.
+func LoadRSAPublicKeyPEM(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.ParseRSAPublicKeyFromPEM(data)
+}
+
+// LoadJWKS reads the RSA signing keys from a local JSON Web Key Set file,
+// keyed by kid. Keys of other types or uses are ignored.
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("auth: parse JWKS %s: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("auth: JWKS key %q: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("auth: JWKS key %q: invalid exponent: %w", k.Kid, err)
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("auth: JWKS key %q: unsupported exponent", k.Kid)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("auth: JWKS %s contains no RS256 signing keys", path)
+	}
+	return keys, nil
+}
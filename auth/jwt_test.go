@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("auth-test-secret")
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// validClaims returns claims that pass a validator expecting issuer "shop"
+// and audience "api", with the given changes applied.
+func validClaims(changes jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub":         "user-1",
+		"roles":       []string{"customer"},
+		"customer_id": 7,
+		"iss":         "shop",
+		"aud":         "api",
+		"exp":         time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewValidatorWithoutKeys(t *testing.T) {
+	if _, err := NewValidator(Config{Issuer: "shop"}); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("got %v, want ErrNoKeys", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rsaKey := generateKey(t)
+	otherKey := generateKey(t)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &rsaKey.PublicKey)})
+
+	v, err := NewValidator(Config{
+		HS256Secret: testSecret,
+		RS256Keys:   map[string]*rsa.PublicKey{"k1": &rsaKey.PublicKey},
+		Issuer:      "shop",
+		Audience:    "api",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaOnly, err := NewValidator(Config{RS256Keys: map[string]*rsa.PublicKey{"": &rsaKey.PublicKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		validator *Validator
+		token     string
+		ok        bool
+	}{
+		{"HS256", v, sign(t, jwt.SigningMethodHS256, "", validClaims(nil), testSecret), true},
+		{"RS256", v, sign(t, jwt.SigningMethodRS256, "k1", validClaims(nil), rsaKey), true},
+		{"RS256 without kid", rsaOnly, sign(t, jwt.SigningMethodRS256, "", validClaims(nil), rsaKey), true},
+		{"wrong secret", v, sign(t, jwt.SigningMethodHS256, "", validClaims(nil), []byte("other")), false},
+		{"unknown kid", v, sign(t, jwt.SigningMethodRS256, "k2", validClaims(nil), rsaKey), false},
+		{"wrong RSA key", v, sign(t, jwt.SigningMethodRS256, "k1", validClaims(nil), otherKey), false},
+		{"expired", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()}), testSecret), false},
+		{"no expiry", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"exp": nil}), testSecret), false},
+		{"not yet valid", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"nbf": now.Add(time.Hour).Unix()}), testSecret), false},
+		{"already valid", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"nbf": now.Add(-time.Minute).Unix()}), testSecret), true},
+		{"wrong issuer", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"iss": "elsewhere"}), testSecret), false},
+		{"no issuer", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"iss": nil}), testSecret), false},
+		{"wrong audience", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"aud": "admin"}), testSecret), false},
+		{"audience in a list", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"aud": []string{"admin", "api"}}), testSecret), true},
+		{"no subject", v, sign(t, jwt.SigningMethodHS256, "", validClaims(jwt.MapClaims{"sub": nil}), testSecret), false},
+		{"alg none", v, sign(t, jwt.SigningMethodNone, "", validClaims(nil), jwt.UnsafeAllowNoneSignatureType), false},
+		// A token signed with HS256 using the public key as the secret must
+		// not pass as an RS256 token.
+		{"HS256 signed with the RSA public key", rsaOnly, sign(t, jwt.SigningMethodHS256, "", validClaims(nil), publicPEM), false},
+		{"HS256 signed with the RSA public key and an HS256 secret configured", v, sign(t, jwt.SigningMethodHS256, "k1", validClaims(nil), publicPEM), false},
+		{"HS384", v, sign(t, jwt.SigningMethodHS384, "", validClaims(nil), testSecret), false},
+		{"malformed", v, "not.a.token", false},
+	}
+
+	for _, tt := range tests {
+		p, err := tt.validator.Validate(tt.token)
+		if tt.ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: token accepted as %+v", tt.name, p)
+		}
+	}
+}
+
+func TestValidatePrincipal(t *testing.T) {
+	v, err := NewValidator(Config{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := v.Validate(sign(t, jwt.SigningMethodHS256, "", validClaims(nil), testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "user-1" || p.CustomerID != 7 || !p.HasRole("customer") || p.IsAPIKey() {
+		t.Errorf("got %+v", p)
+	}
+}
+
+func mustMarshalPKIX(t *testing.T, key *rsa.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func writeJWKS(t *testing.T, keys ...jwk) string {
+	t.Helper()
+	data, err := json.Marshal(map[string][]jwk{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, "jwks.json", data)
+}
+
+// TestJWKS validates RS256 tokens against keys loaded from a JWKS file.
+func TestJWKS(t *testing.T) {
+	signing := generateKey(t)
+	encryption := generateKey(t)
+
+	sig := rsaJWK("sig", &signing.PublicKey)
+	sig.Use, sig.Alg = "sig", "RS256"
+	enc := rsaJWK("enc", &encryption.PublicKey)
+	enc.Use = "enc"
+	ps256 := rsaJWK("ps256", &encryption.PublicKey)
+	ps256.Alg = "PS256"
+
+	keys, err := LoadJWKS(writeJWKS(t, sig, enc, ps256, jwk{Kty: "EC", Kid: "ec"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys["sig"] == nil || keys["sig"].N.Cmp(signing.N) != 0 || keys["sig"].E != signing.E {
+		t.Fatalf("got keys %v, want only sig", keys)
+	}
+
+	v, err := NewValidator(Config{RS256Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Validate(sign(t, jwt.SigningMethodRS256, "sig", validClaims(nil), signing)); err != nil {
+		t.Errorf("token signed with the JWKS key: %v", err)
+	}
+	if _, err := v.Validate(sign(t, jwt.SigningMethodRS256, "enc", validClaims(nil), encryption)); err == nil {
+		t.Error("token signed with an encryption key was accepted")
+	}
+}
+
+func TestLoadJWKSErrors(t *testing.T) {
+	key := generateKey(t)
+	valid := rsaJWK("k1", &key.PublicKey)
+
+	badModulus := valid
+	badModulus.N = "not base64!"
+	badExponent := valid
+	badExponent.E = "not base64!"
+	smallExponent := valid
+	smallExponent.E = base64.RawURLEncoding.EncodeToString([]byte{1})
+	hugeExponent := valid
+	hugeExponent.E = base64.RawURLEncoding.EncodeToString([]byte{1, 0, 0, 0, 0})
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.json"), "no such file"},
+		{"invalid JSON", writeFile(t, "jwks.json", []byte("{")), "parse JWKS"},
+		{"no keys", writeJWKS(t), "no RS256 signing keys"},
+		{"only unusable keys", writeJWKS(t, jwk{Kty: "EC", Kid: "ec"}), "no RS256 signing keys"},
+		{"invalid modulus", writeJWKS(t, badModulus), "invalid modulus"},
+		{"invalid exponent", writeJWKS(t, badExponent), "invalid exponent"},
+		{"exponent too small", writeJWKS(t, smallExponent), "unsupported exponent"},
+		{"exponent too large", writeJWKS(t, hugeExponent), "unsupported exponent"},
+	}
+
+	for _, tt := range tests {
+		keys, err := LoadJWKS(tt.path)
+		if err == nil {
+			t.Errorf("%s: got keys %v, want an error", tt.name, keys)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %q, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadRSAPublicKeyPEM(t *testing.T) {
+	key := generateKey(t)
+	path := writeFile(t, "key.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &key.PublicKey)}))
+
+	loaded, err := LoadRSAPublicKeyPEM(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.N.Cmp(key.N) != 0 || loaded.E != key.E {
+		t.Error("loaded key differs from the written one")
+	}
+
+	if _, err := LoadRSAPublicKeyPEM(writeFile(t, "bad.pem", []byte("not a key"))); err == nil {
+		t.Error("invalid PEM accepted")
+	}
+}
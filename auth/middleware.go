@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware authenticates requests and stores the principal in the request
+// context. Callers identify themselves with an "Authorization: Bearer" token
+// checked by v, or with an X-API-Key header resolved by keys; either may be
+// nil to disable that method. Routes whose name is listed in public may be
+// called anonymously as the Anonymous principal, though credentials sent to
+// them are still checked; every other route answers 401 without valid
+// credentials.
+func Middleware(v *Validator, keys APIKeyStore, public ...string) mux.MiddlewareFunc {
+	publicRoutes := make(map[string]bool, len(public))
+	for _, name := range public {
+		publicRoutes[name] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var principal *Principal
+			var err error
+
+			apiKey := r.Header.Get(APIKeyHeader)
+			token, hasToken := bearerToken(r)
+			switch {
+			case apiKey != "" && keys != nil:
+				principal, err = keys.LookupAPIKey(HashAPIKey(apiKey))
+			case hasToken && v != nil:
+				principal, err = v.Validate(token)
+			case apiKey == "" && !hasToken:
+				if route := mux.CurrentRoute(r); route != nil && publicRoutes[route.GetName()] {
+					principal = Anonymous
+					break
+				}
+				unauthorized(w)
+				return
+			default:
+				unauthorized(w)
+				return
+			}
+
+			if err != nil {
+				if !errors.Is(err, ErrInvalidAPIKey) {
+					log.Println("Rejected credentials:", err) // Log error
+				}
+				unauthorized(w)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[len("Bearer "):])
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
@@ -0,0 +1,265 @@
+// Package client is a Go client for the products and orders API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-productnorder/apiversion"
+	"api-productnorder/auth"
+	"api-productnorder/idempotency"
+)
+
+// Default retry settings, used when Config leaves them zero.
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// maxBackoff caps the wait between attempts, including waits asked for
+	// with Retry-After.
+	maxBackoff = 10 * time.Second
+)
+
+// Config sets up a Client.
+type Config struct {
+	// BaseURL is the server address, for example http://localhost:8080.
+	BaseURL string
+	// HTTPClient sends the requests. Nil uses http.DefaultClient.
+	HTTPClient *http.Client
+	// Token is sent as a bearer token when set.
+	Token string
+	// APIKey is sent in the X-API-Key header when set.
+	APIKey string
+	// MaxRetries is how many times a request is retried after a transient
+	// failure. Zero uses DefaultMaxRetries; a negative value disables retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry. It doubles with each
+	// retry, with jitter. Zero uses DefaultRetryBackoff.
+	RetryBackoff time.Duration
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	apiKey     string
+	maxRetries int
+	backoff    time.Duration
+}
+
+// New returns a Client for the server at cfg.BaseURL.
+func New(cfg Config) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		httpClient: cfg.HTTPClient,
+		token:      cfg.Token,
+		apiKey:     cfg.APIKey,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = DefaultMaxRetries
+	}
+	if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.backoff <= 0 {
+		c.backoff = DefaultRetryBackoff
+	}
+	return c
+}
+
+// Error is a response with an error status. The API reports errors as plain
+// text, which becomes Message.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response from the API.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// envelope is the shape of every successful response.
+type envelope struct {
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+}
+
+// do sends a request with in, if any, as its JSON body and decodes the data
+// of the response into out, retrying transient failures. POST requests carry
+// an Idempotency-Key that stays the same across retries, so the server
+// replays the first response instead of repeating the request.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	var key string
+	if method == http.MethodPost {
+		var err error
+		key, err = newIdempotencyKey()
+		if err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, retryAfter, err := c.send(ctx, method, path, key, body, out)
+		if err == nil || !retry || attempt >= c.maxRetries {
+			return err
+		}
+
+		wait := c.backoffFor(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt. When it fails, send reports whether the attempt
+// may succeed if repeated and the wait the server asked for with
+// Retry-After, if any.
+func (c *Client) send(ctx context.Context, method, path, key string, body []byte, out interface{}) (bool, time.Duration, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(apiversion.Header, strconv.Itoa(apiversion.V2))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, c.apiKey)
+	}
+
+	// A request that got no complete response is retried unless the
+	// caller gave up on it.
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ctx.Err() == nil, 0, err
+	}
+	if resp.StatusCode >= 400 {
+		retryAfter := resp.Header.Get("Retry-After")
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: errorMessage(resp, data)}
+		return retryable(resp.StatusCode, retryAfter != ""), parseRetryAfter(retryAfter), apiErr
+	}
+
+	if out == nil {
+		return false, 0, nil
+	}
+	var response envelope
+	if err := json.Unmarshal(data, &response); err != nil {
+		return false, 0, fmt.Errorf("api: decoding response: %w", err)
+	}
+	if err := json.Unmarshal(response.Data, out); err != nil {
+		return false, 0, fmt.Errorf("api: decoding response data: %w", err)
+	}
+	return false, 0, nil
+}
+
+// errorMessage returns the message of an error response: the text of a plain
+// text body, or the message of a JSON one.
+func errorMessage(resp *http.Response, data []byte) string {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var response envelope
+		if err := json.Unmarshal(data, &response); err == nil && response.Message != "" {
+			return response.Message
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// retryable reports whether an error response may succeed if repeated: the
+// caller was rate limited, the server failed, or a request with the same
+// idempotency key is still running. The last is a 409 with Retry-After,
+// which sets it apart from conflicts with the stored data.
+func retryable(status int, hasRetryAfter bool) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		return hasRetryAfter
+	}
+	return false
+}
+
+// backoffFor returns the wait before retry attempt+1: the base backoff
+// doubled for each earlier retry, with up to 50% jitter either way.
+func (c *Client) backoffFor(attempt int) time.Duration {
+	wait := float64(c.backoff) * math.Pow(2, float64(attempt))
+	wait *= 0.5 + mathrand.Float64()
+	if wait > float64(maxBackoff) {
+		return maxBackoff
+	}
+	return time.Duration(wait)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
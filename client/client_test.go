@@ -0,0 +1,285 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"api-productnorder/idempotency"
+	"api-productnorder/models"
+	"api-productnorder/repository"
+	"api-productnorder/router"
+)
+
+// newServer serves the real router from an empty in-memory store, with
+// idempotency keys enabled as in main.go. wrap, when set, sits in front of
+// the router.
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	var h http.Handler = router.New(router.Config{
+		Store:            repository.NewMemory(),
+		IdempotencyStore: idempotency.NewMemoryStore(),
+		IdempotencyTTL:   time.Hour,
+	})
+	if wrap != nil {
+		h = wrap(h)
+	}
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(server *httptest.Server) *Client {
+	return New(Config{BaseURL: server.URL, RetryBackoff: time.Millisecond})
+}
+
+func TestProductsAndOrders(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(newServer(t, nil))
+
+	products, err := c.ListProducts(ctx)
+	if err != nil || len(products) != 0 {
+		t.Fatalf("ListProducts on an empty store: got %v, %v", products, err)
+	}
+
+	product, err := c.CreateProduct(ctx, ProductInput{Name: "Kopi", Price: 25000, Stock: 5, ReorderThreshold: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.ID == 0 || product.StockPolicy != models.StockPolicyReject {
+		t.Errorf("CreateProduct: got %+v", product)
+	}
+
+	product, err = c.UpdateProduct(ctx, product.ID, ProductInput{Name: "Kopi Arabika", Price: 30000, Stock: 5, ReorderThreshold: 2})
+	if err != nil || product.Name != "Kopi Arabika" {
+		t.Fatalf("UpdateProduct: got %+v, %v", product, err)
+	}
+
+	result, err := c.AdjustStock(ctx, product.ID, StockAdjustmentInput{Delta: 5, Reason: "restock"})
+	if err != nil || result.Product.Stock != 10 || result.Adjustment.Delta != 5 {
+		t.Fatalf("AdjustStock: got %+v, %v", result, err)
+	}
+
+	products, err = c.ListProducts(ctx)
+	if err != nil || len(products) != 1 {
+		t.Fatalf("ListProducts: got %v, %v", products, err)
+	}
+	if _, err := c.ListLowStockProducts(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	address := &models.OrderAddress{Name: "Budi", Line1: "Jl. Merdeka 1", City: "Jakarta", PostalCode: "10110", Country: "ID"}
+	order, err := c.CreateOrder(ctx, OrderInput{Products: []OrderItem{{ID: product.ID, Quantity: 2}}, ShippingAddress: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.ID == nil || len(order.Lines) != 1 || order.Lines[0].Quantity != 2 || order.Lines[0].LineTotal != 60000 {
+		t.Fatalf("CreateOrder: got %+v", order)
+	}
+	created := order
+	if order.BillingAddress == nil || order.BillingAddress.City != "Jakarta" {
+		t.Errorf("CreateOrder: billing address %+v, want the shipping address", order.BillingAddress)
+	}
+
+	order, err = c.UpdateOrderItems(ctx, *order.ID, OrderItemsInput{Products: []OrderItem{{ID: product.ID, Quantity: 3}}})
+	if err != nil || order.Lines[0].Quantity != 3 {
+		t.Fatalf("UpdateOrderItems: got %+v, %v", order, err)
+	}
+
+	billing := *address
+	billing.City = "Bandung"
+	order, err = c.UpdateOrderAddresses(ctx, *order.ID, nil, &billing)
+	if err != nil || order.BillingAddress.City != "Bandung" || order.ShippingAddress.City != "Jakarta" {
+		t.Fatalf("UpdateOrderAddresses: got %+v, %v", order, err)
+	}
+
+	got, err := c.GetOrder(ctx, *order.ID)
+	if err != nil || *got.ID != *order.ID {
+		t.Fatalf("GetOrder: got %+v, %v", got, err)
+	}
+	if created.CreatedAt == nil || !got.CreatedAt.Equal(*created.CreatedAt) {
+		t.Errorf("GetOrder: created_at %v, CreateOrder returned %v", got.CreatedAt, created.CreatedAt)
+	}
+	orders, err := c.ListOrders(ctx)
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("ListOrders: got %v, %v", orders, err)
+	}
+
+	product, err = c.GetProduct(ctx, product.ID)
+	if err != nil || product.Stock != 7 || product.Sold != 3 {
+		t.Fatalf("GetProduct: got %+v, %v", product, err)
+	}
+
+	if _, err := c.DeleteOrder(ctx, *order.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetOrder(ctx, *order.ID); !IsNotFound(err) {
+		t.Errorf("GetOrder after delete: got %v, want not found", err)
+	}
+	if _, err := c.DeleteProduct(ctx, product.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(newServer(t, nil))
+
+	_, err := c.GetProduct(ctx, 99)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Product not found" {
+		t.Errorf("GetProduct: got %v, want 404 Product not found", err)
+	}
+
+	product, err := c.CreateProduct(ctx, ProductInput{Name: "Kopi", Price: 25000, Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CreateOrder(ctx, OrderInput{Products: []OrderItem{{ID: product.ID, Quantity: 2}}})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "Product out of stock" {
+		t.Errorf("CreateOrder: got %v, want 400 Product out of stock", err)
+	}
+
+	_, err = c.AdjustStock(ctx, product.ID, StockAdjustmentInput{Delta: -5, Reason: "shrinkage"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("AdjustStock: got %v, want 409", err)
+	}
+}
+
+// flaky fails the first n requests with status before passing requests on.
+// It records the Idempotency-Key of every request.
+type flaky struct {
+	mu       sync.Mutex
+	n        int
+	status   int
+	attempts int
+	keys     []string
+}
+
+func (f *flaky) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.attempts++
+		f.keys = append(f.keys, r.Header.Get(idempotency.Header))
+		fail := f.attempts <= f.n
+		f.mu.Unlock()
+
+		if fail {
+			http.Error(w, http.StatusText(f.status), f.status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable} {
+		f := &flaky{n: 2, status: status}
+		c := newClient(newServer(t, f.wrap))
+
+		if _, err := c.CreateProduct(context.Background(), ProductInput{Name: "Kopi", Price: 25000, Stock: 5}); err != nil {
+			t.Errorf("status %d: %v", status, err)
+		}
+		if f.attempts != 3 {
+			t.Errorf("status %d: %d attempts, want 3", status, f.attempts)
+		}
+		for _, key := range f.keys {
+			if key == "" || key != f.keys[0] {
+				t.Errorf("status %d: idempotency keys %q, want one key reused", status, f.keys)
+				break
+			}
+		}
+	}
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	f := &flaky{}
+	c := newClient(newServer(t, f.wrap))
+
+	_, err := c.CreateOrder(context.Background(), OrderInput{})
+	if err == nil {
+		t.Fatal("CreateOrder with no products succeeded")
+	}
+	if f.attempts != 1 {
+		t.Errorf("%d attempts, want 1", f.attempts)
+	}
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	f := &flaky{n: 100, status: http.StatusServiceUnavailable}
+	c := New(Config{BaseURL: newServer(t, f.wrap).URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	_, err := c.ListOrders(context.Background())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want the last 503", err)
+	}
+	if f.attempts != 3 {
+		t.Errorf("%d attempts, want 3", f.attempts)
+	}
+}
+
+// TestRetryDoesNotRepeatOrder loses the response to the first order after
+// the server has placed it. The retry carries the same idempotency key, so
+// the server replays the first response instead of placing a second order.
+func TestRetryDoesNotRepeatOrder(t *testing.T) {
+	var mu sync.Mutex
+	lost := false
+	server := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			lose := r.Method == http.MethodPost && r.URL.Path == "/api/orders" && !lost
+			lost = lost || lose
+			mu.Unlock()
+
+			if lose {
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				http.Error(w, "Bad Gateway", http.StatusBadGateway)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(server)
+	ctx := context.Background()
+
+	product, err := c.CreateProduct(ctx, ProductInput{Name: "Kopi", Price: 25000, Stock: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateOrder(ctx, OrderInput{Products: []OrderItem{{ID: product.ID, Quantity: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := c.ListOrders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 {
+		t.Errorf("%d orders placed, want 1", len(orders))
+	}
+	if product, _ := c.GetProduct(ctx, product.ID); product.Stock != 4 {
+		t.Errorf("stock %d, want 4", product.Stock)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	f := &flaky{n: 100, status: http.StatusServiceUnavailable}
+	c := New(Config{BaseURL: newServer(t, f.wrap).URL, MaxRetries: 100, RetryBackoff: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.ListOrders(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %v, want soon after the deadline", elapsed)
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"api-productnorder/models"
+)
+
+// OrderItem is a product and the quantity ordered.
+type OrderItem struct {
+	ID       int64 `json:"id"`
+	Quantity int64 `json:"quantity"`
+}
+
+// OrderInput is the body of a new order.
+type OrderInput struct {
+	Products []OrderItem `json:"products"`
+	// Allocation selects the warehouses that fulfil the order. Nil uses the
+	// server's default rule.
+	Allocation *models.AllocationRequest `json:"allocation,omitempty"`
+	// CustomerID is the customer the order is for, or zero for none.
+	// Customers calling with their own token may leave it zero.
+	CustomerID      int64                `json:"customer_id,omitempty"`
+	ShippingAddress *models.OrderAddress `json:"shipping_address,omitempty"`
+	// BillingAddress defaults to the shipping address.
+	BillingAddress *models.OrderAddress `json:"billing_address,omitempty"`
+	// Currency is the currency the order is priced in. Empty uses the
+	// currency all the products share.
+	Currency string `json:"currency,omitempty"`
+}
+
+// OrderItemsInput is the body of an order items update.
+type OrderItemsInput struct {
+	// Products replaces the items of the order. Products with a zero
+	// quantity are removed.
+	Products   []OrderItem               `json:"products"`
+	Allocation *models.AllocationRequest `json:"allocation,omitempty"`
+}
+
+type orderAddressesInput struct {
+	ShippingAddress *models.OrderAddress `json:"shipping_address,omitempty"`
+	BillingAddress  *models.OrderAddress `json:"billing_address,omitempty"`
+}
+
+// ListOrders returns the orders the caller can see: every order for staff,
+// or the caller's own orders for a customer.
+func (c *Client) ListOrders(ctx context.Context) ([]models.Order, error) {
+	var orders []models.Order
+	err := c.do(ctx, http.MethodGet, "/api/orders", nil, &orders)
+	return orders, err
+}
+
+// GetOrder returns the order with the given ID.
+func (c *Client) GetOrder(ctx context.Context, id int64) (models.Order, error) {
+	var order models.Order
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/orders/%d", id), nil, &order)
+	return order, err
+}
+
+// CreateOrder places an order. A retried attempt reuses the idempotency key
+// of the first, so an order is never placed twice.
+func (c *Client) CreateOrder(ctx context.Context, input OrderInput) (models.Order, error) {
+	var order models.Order
+	err := c.do(ctx, http.MethodPost, "/api/orders", input, &order)
+	return order, err
+}
+
+// UpdateOrderItems replaces the items of a pending order.
+func (c *Client) UpdateOrderItems(ctx context.Context, id int64, input OrderItemsInput) (models.Order, error) {
+	var order models.Order
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/orders/%d/items", id), input, &order)
+	return order, err
+}
+
+// UpdateOrderAddresses changes the shipping address, the billing address or
+// both of a pending order. A nil address is left as it is.
+func (c *Client) UpdateOrderAddresses(ctx context.Context, id int64, shipping, billing *models.OrderAddress) (models.Order, error) {
+	var order models.Order
+	input := orderAddressesInput{ShippingAddress: shipping, BillingAddress: billing}
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/orders/%d/addresses", id), input, &order)
+	return order, err
+}
+
+// DeleteOrder deletes the order with the given ID and returns it.
+func (c *Client) DeleteOrder(ctx context.Context, id int64) (models.Order, error) {
+	var order models.Order
+	err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/orders/%d", id), nil, &order)
+	return order, err
+}
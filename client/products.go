@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"api-productnorder/models"
+)
+
+// ProductInput is the body of a product create or update.
+type ProductInput struct {
+	Name             string `json:"name"`
+	Price            int64  `json:"price"`
+	Stock            int64  `json:"stock"`
+	ReorderThreshold int64  `json:"reorder_threshold"`
+	// Currency is the currency of Price. Empty means currency.Default.
+	Currency string `json:"currency,omitempty"`
+	// Prices are fixed prices in other currencies.
+	Prices []models.ProductPrice `json:"prices,omitempty"`
+	// StockPolicy is one of the models.StockPolicy values. Empty means
+	// models.StockPolicyReject.
+	StockPolicy string `json:"stock_policy,omitempty"`
+}
+
+// StockAdjustmentInput is the body of a stock adjustment.
+type StockAdjustmentInput struct {
+	Delta  int64  `json:"delta"`
+	Reason string `json:"reason"`
+	// WarehouseID is the warehouse whose stock changes, or zero for stock
+	// not assigned to a warehouse.
+	WarehouseID int64 `json:"warehouse_id,omitempty"`
+}
+
+// ListProducts returns every product. Unlike the API, which answers 404 when
+// there are none, it returns an empty list.
+func (c *Client) ListProducts(ctx context.Context) ([]models.Product, error) {
+	var products []models.Product
+	err := c.do(ctx, http.MethodGet, "/api/products", nil, &products)
+	if IsNotFound(err) {
+		return []models.Product{}, nil
+	}
+	return products, err
+}
+
+// ListLowStockProducts returns the products whose stock is below their
+// reorder threshold.
+func (c *Client) ListLowStockProducts(ctx context.Context) ([]models.Product, error) {
+	var products []models.Product
+	err := c.do(ctx, http.MethodGet, "/api/products/low-stock", nil, &products)
+	return products, err
+}
+
+// GetProduct returns the product with the given ID.
+func (c *Client) GetProduct(ctx context.Context, id int64) (models.Product, error) {
+	var product models.Product
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/products/%d", id), nil, &product)
+	return product, err
+}
+
+// CreateProduct creates a product.
+func (c *Client) CreateProduct(ctx context.Context, input ProductInput) (models.Product, error) {
+	var product models.Product
+	err := c.do(ctx, http.MethodPost, "/api/products", input, &product)
+	return product, err
+}
+
+// UpdateProduct replaces the fields of the product with the given ID.
+func (c *Client) UpdateProduct(ctx context.Context, id int64, input ProductInput) (models.Product, error) {
+	var product models.Product
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/products/%d", id), input, &product)
+	return product, err
+}
+
+// DeleteProduct deletes the product with the given ID and returns it.
+func (c *Client) DeleteProduct(ctx context.Context, id int64) (models.Product, error) {
+	var product models.Product
+	err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/products/%d", id), nil, &product)
+	return product, err
+}
+
+// AdjustStock changes the stock of the product with the given ID by a
+// relative amount.
+func (c *Client) AdjustStock(ctx context.Context, id int64, input StockAdjustmentInput) (models.StockAdjustmentResult, error) {
+	var result models.StockAdjustmentResult
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/products/%d/stock-adjustments", id), input, &result)
+	return result, err
+}
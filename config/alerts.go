@@ -0,0 +1,9 @@
+package config
+
+import "os"
+
+// AlertWebhookURL returns the URL low-stock alerts are posted to. When empty,
+// alerts are written to the log instead.
+func AlertWebhookURL() string {
+	return os.Getenv("ALERT_WEBHOOK_URL")
+}
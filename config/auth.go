@@ -0,0 +1,46 @@
+package config
+
+import (
+	"api-productnorder/auth"
+	"crypto/rsa"
+	"os"
+)
+
+// AuthDisabled reports whether AUTH_DISABLED=true turns off authentication,
+// which is only meant for local development.
+func AuthDisabled() bool {
+	return os.Getenv("AUTH_DISABLED") == "true"
+}
+
+// AuthConfig builds the token validation settings from the environment:
+// JWT_HS256_SECRET for shared-secret tokens, JWT_RS256_PUBLIC_KEY_FILE (PEM)
+// and/or JWT_JWKS_FILE for RSA tokens, and the optional JWT_ISSUER and
+// JWT_AUDIENCE claims.
+func AuthConfig() (auth.Config, error) {
+	cfg := auth.Config{
+		HS256Secret: []byte(os.Getenv("JWT_HS256_SECRET")),
+		RS256Keys:   make(map[string]*rsa.PublicKey),
+		Issuer:      os.Getenv("JWT_ISSUER"),
+		Audience:    os.Getenv("JWT_AUDIENCE"),
+	}
+
+	if path := os.Getenv("JWT_JWKS_FILE"); path != "" {
+		keys, err := auth.LoadJWKS(path)
+		if err != nil {
+			return auth.Config{}, err
+		}
+		for kid, key := range keys {
+			cfg.RS256Keys[kid] = key
+		}
+	}
+
+	if path := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		key, err := auth.LoadRSAPublicKeyPEM(path)
+		if err != nil {
+			return auth.Config{}, err
+		}
+		cfg.RS256Keys[""] = key
+	}
+
+	return cfg, nil
+}
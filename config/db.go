@@ -3,27 +3,128 @@ package config
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"sync"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
+// Database drivers selectable with DB_DRIVER.
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+var (
+	dbMu   sync.Mutex
+	dbPool *sql.DB
+)
+
+// DBDriver returns the database driver named by DB_DRIVER, mysql by default.
+func DBDriver() (string, error) {
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", DriverMySQL:
+		return DriverMySQL, nil
+	case DriverSQLite:
+		return DriverSQLite, nil
+	case DriverPostgres:
+		return DriverPostgres, nil
+	default:
+		return "", fmt.Errorf("unknown DB_DRIVER %q (want %s, %s or %s)", driver, DriverMySQL, DriverSQLite, DriverPostgres)
+	}
+}
+
+// ConnectDB returns the shared connection pool, opening it on first use.
+// Callers must not close it; CloseDB does that once on shutdown. If the
+// database cannot be reached, the next call tries again.
 func ConnectDB() (*sql.DB, error) {
-	dbHost := "localhost"
-	dbPort := "3307"
-	dbUser := "root"
-	dbPassword := "kevin123"
-	dbName := "product_order_db" // nama database yang telah dibuat
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
-	db, err := sql.Open("mysql", dsn)
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	if dbPool != nil {
+		return dbPool, nil
+	}
+
+	driver, err := DBDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	// The pgx driver registers itself under its own name.
+	driverName := driver
+	var dsn string
+	switch driver {
+	case DriverSQLite:
+		dsn = sqliteDSN()
+	case DriverPostgres:
+		driverName = "pgx"
+		dsn = postgresDSN()
+	default:
+		dbHost := "localhost"
+		dbPort := "3307"
+		dbUser := "root"
+		dbPassword := "kevin123"
+		dbName := "product_order_db" // nama database yang telah dibuat
+
+		// parseTime returns DATETIME columns as time values, read as UTC,
+		// the zone the repositories store timestamps in.
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=UTC", dbUser, dbPassword, dbHost, dbPort, dbName)
+	}
+
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, err
 	}
 
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	return db, nil
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	dbPool = db
+	return dbPool, nil
+}
+
+// sqliteDSN opens the file named by DB_PATH, product_order.db by default.
+// Foreign keys are off in SQLite unless asked for, and _txlock=immediate
+// makes transactions take the write lock up front, which the repository
+// relies on in place of SELECT ... FOR UPDATE. Writers queue for up to five
+// seconds rather than failing at once while another holds the lock.
+func sqliteDSN() string {
+	path := os.Getenv("DB_PATH")
+	if path == "" {
+		path = "product_order.db"
+	}
+	return "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+}
+
+// postgresDSN returns DATABASE_URL, or a local product_order_db database by
+// default.
+func postgresDSN() string {
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		return url
+	}
+	return "postgres://postgres@localhost:5432/product_order_db?sslmode=disable"
+}
+
+// CloseDB closes the shared connection pool, if it was opened.
+func CloseDB() error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	if dbPool == nil {
+		return nil
+	}
+	err := dbPool.Close()
+	dbPool = nil
+	return err
 }
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// IdempotencyTTL returns how long idempotency keys are remembered, read from
+// IDEMPOTENCY_TTL as a Go duration such as "24h". It defaults to 24 hours.
+func IdempotencyTTL() (time.Duration, error) {
+	value := os.Getenv("IDEMPOTENCY_TTL")
+	if value == "" {
+		return 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"api-productnorder/models"
+	"os"
+)
+
+// DefaultAllocationRule returns the warehouse allocation rule used when an
+// order does not choose one. It is read from ALLOCATION_RULE and falls back
+// to the preferred rule.
+func DefaultAllocationRule() string {
+	if rule := os.Getenv("ALLOCATION_RULE"); models.ValidAllocationRule(rule) {
+		return rule
+	}
+	return models.AllocationPreferred
+}
@@ -0,0 +1,9 @@
+package config
+
+import "os"
+
+// AutoMigrate reports whether AUTO_MIGRATE=true applies pending migrations
+// when the server starts.
+func AutoMigrate() bool {
+	return os.Getenv("AUTO_MIGRATE") == "true"
+}
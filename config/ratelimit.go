@@ -0,0 +1,59 @@
+package config
+
+import (
+	"api-productnorder/ratelimit"
+	"os"
+	"strings"
+)
+
+// RateLimitDisabled reports whether RATE_LIMIT_DISABLED=true turns off rate limiting.
+func RateLimitDisabled() bool {
+	return os.Getenv("RATE_LIMIT_DISABLED") == "true"
+}
+
+// RateLimits returns defaults with overrides from the environment.
+// RATE_LIMIT_DEFAULT replaces the default limit, RATE_LIMIT_IP the limit per
+// remote address, and RATE_LIMIT_<ROUTE> the limit of a single route, where
+// <ROUTE> is the route name in upper case with dots and dashes turned into
+// underscores; for example RATE_LIMIT_ORDERS_CREATE=20/1m. Values are written as "<requests>/<period>".
+func RateLimits(defaults ratelimit.Config, routes []string) (ratelimit.Config, error) {
+	cfg := ratelimit.Config{
+		Default: defaults.Default,
+		Routes:  make(map[string]ratelimit.Limit, len(defaults.Routes)),
+		IP:      defaults.IP,
+	}
+	for route, limit := range defaults.Routes {
+		cfg.Routes[route] = limit
+	}
+
+	if value := os.Getenv("RATE_LIMIT_DEFAULT"); value != "" {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return ratelimit.Config{}, err
+		}
+		cfg.Default = limit
+	}
+
+	if value := os.Getenv("RATE_LIMIT_IP"); value != "" {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return ratelimit.Config{}, err
+		}
+		cfg.IP = limit
+	}
+
+	replacer := strings.NewReplacer(".", "_", "-", "_")
+	for _, route := range routes {
+		value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(replacer.Replace(route)))
+		if value == "" {
+			continue
+		}
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return ratelimit.Config{}, err
+		}
+		cfg.Routes[route] = limit
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Server holds the listen address and timeouts of the HTTP server.
+type Server struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// DrainDelay is how long readiness reports draining before the server
+	// stops accepting connections, giving load balancers time to notice.
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may run after a
+	// shutdown signal before the server closes them.
+	ShutdownTimeout time.Duration
+}
+
+// ServerConfig returns the server settings. SERVER_ADDR sets the listen
+// address, and SERVER_READ_HEADER_TIMEOUT, SERVER_READ_TIMEOUT,
+// SERVER_WRITE_TIMEOUT, SERVER_IDLE_TIMEOUT, SHUTDOWN_DRAIN_DELAY and
+// SHUTDOWN_TIMEOUT take Go durations such as "15s".
+func ServerConfig() (Server, error) {
+	cfg := Server{
+		Addr:              ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+	}
+	if addr := os.Getenv("SERVER_ADDR"); addr != "" {
+		cfg.Addr = addr
+	}
+
+	durations := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", &cfg.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", &cfg.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", &cfg.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", &cfg.IdleTimeout},
+		{"SHUTDOWN_DRAIN_DELAY", &cfg.DrainDelay},
+		{"SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout},
+	}
+	for _, d := range durations {
+		value := os.Getenv(d.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return Server{}, fmt.Errorf("%s: %w", d.name, err)
+		}
+		*d.value = parsed
+	}
+
+	return cfg, nil
+}
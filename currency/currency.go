@@ -0,0 +1,64 @@
+// Package currency validates currency codes and converts amounts between
+// currencies for reporting. Amounts are whole units of their currency, such
+// as rupiah or dollars, never cents.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Default is the currency of products created without one, and the currency
+// exchange rates are quoted against.
+const Default = "IDR"
+
+// ErrNoRate is returned when an amount cannot be converted because a
+// currency has no exchange rate.
+var ErrNoRate = errors.New("no exchange rate")
+
+// Valid reports whether code looks like an ISO 4217 code: three upper-case
+// letters.
+func Valid(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Rates maps a currency to the value of one unit of it in Default. Default
+// itself is always worth 1.
+type Rates map[string]float64
+
+// Convert converts amount from one currency to another through Default,
+// rounding to the nearest unit.
+func (r Rates) Convert(amount int64, from, to string) (int64, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, err := r.rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := r.rate(to)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(float64(amount) * fromRate / toRate)), nil
+}
+
+func (r Rates) rate(code string) (float64, error) {
+	if code == Default {
+		return 1, nil
+	}
+	rate, ok := r[code]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("%w for %s", ErrNoRate, code)
+	}
+	return rate, nil
+}
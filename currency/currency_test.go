@@ -0,0 +1,59 @@
+package currency
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for code, want := range map[string]bool{
+		"IDR": true, "USD": true,
+		"usd": false, "US": false, "USDT": false, "U$D": false, "": false,
+	} {
+		if got := Valid(code); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rates := Rates{"USD": 16000, "EUR": 17500, "JPY": 105.5, "XXX": 0}
+
+	tests := []struct {
+		name     string
+		rates    Rates
+		amount   int64
+		from, to string
+		want     int64
+		noRate   string
+	}{
+		{name: "same currency", rates: rates, amount: 40, from: "USD", to: "USD", want: 40},
+		{name: "same currency without a rate", amount: 40, from: "GBP", to: "GBP", want: 40},
+		{name: "default without rates", amount: 650000, from: Default, to: Default, want: 650000},
+		{name: "into the default", rates: rates, amount: 40, from: "USD", to: Default, want: 640000},
+		{name: "out of the default", rates: rates, amount: 640000, from: Default, to: "USD", want: 40},
+		{name: "cross rate", rates: rates, amount: 100, from: "USD", to: "EUR", want: 91},
+		{name: "fractional rate", rates: rates, amount: 3, from: "JPY", to: Default, want: 317},
+		{name: "half rounds away from zero", rates: rates, amount: 8000, from: Default, to: "USD", want: 1},
+		{name: "just under half rounds down", rates: rates, amount: 7999, from: Default, to: "USD", want: 0},
+		{name: "negative half rounds away from zero", rates: rates, amount: -8000, from: Default, to: "USD", want: -1},
+		{name: "missing source rate", rates: rates, amount: 1, from: "GBP", to: Default, noRate: "GBP"},
+		{name: "missing target rate", rates: rates, amount: 1, from: "USD", to: "GBP", noRate: "GBP"},
+		{name: "zero rate", rates: rates, amount: 1, from: "XXX", to: Default, noRate: "XXX"},
+		{name: "no rates at all", amount: 1, from: Default, to: "USD", noRate: "USD"},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.rates.Convert(tt.amount, tt.from, tt.to)
+		if tt.noRate != "" {
+			if !errors.Is(err, ErrNoRate) || !strings.Contains(err.Error(), tt.noRate) {
+				t.Errorf("%s: got %d, %v, want ErrNoRate for %s", tt.name, got, err, tt.noRate)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package dbtest opens migrated throwaway databases for tests of the SQL
+// store. It is only imported by tests.
+package dbtest
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api-productnorder/migrations"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Dialects are the database dialects Open accepts, named as in
+// repository.Dialect.
+var Dialects = []string{"sqlite", "postgres", "mysql"}
+
+// Open returns a database of the given dialect with every migration applied,
+// opened the way config opens it. SQLite lives in a temporary file. The
+// PostgreSQL and MySQL databases named by POSTGRES_TEST_URL and
+// MYSQL_TEST_URL are rolled back to empty first, so they must be throwaway
+// databases; without the variable the test is skipped.
+func Open(t testing.TB, dialect string) *sql.DB {
+	t.Helper()
+
+	var driver, dsn string
+	var load func() ([]migrations.Migration, error)
+	switch dialect {
+	case "sqlite":
+		driver, load = "sqlite", migrations.SQLite
+		dsn = "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	case "postgres":
+		driver, load = "pgx", migrations.Postgres
+		dsn = testURL(t, "POSTGRES_TEST_URL")
+	case "mysql":
+		driver, load = "mysql", migrations.MySQL
+		cfg, err := mysql.ParseDSN(testURL(t, "MYSQL_TEST_URL"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Timestamps are read back as UTC time values, as in config.
+		cfg.ParseTime = true
+		cfg.Loc = time.UTC
+		dsn = cfg.FormatDSN()
+	default:
+		t.Fatalf("dbtest: unknown dialect %q", dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	all, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := migrations.New(db, all)
+	m.Postgres = dialect == "postgres"
+	if _, err := m.To(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func testURL(t testing.TB, name string) string {
+	t.Helper()
+	url := os.Getenv(name)
+	if url == "" {
+		t.Skip(name + " is not set")
+	}
+	return url
+}
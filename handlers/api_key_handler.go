@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"api-productnorder/auth"
+	"api-productnorder/models"
+	"api-productnorder/policy"
+	"api-productnorder/repository"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyStore resolves API keys presented to the auth middleware against the
+// stored keys and records when each key was last used
+type APIKeyStore struct {
+	Keys repository.APIKeyRepository
+}
+
+func (s APIKeyStore) LookupAPIKey(hash string) (*auth.Principal, error) {
+	key, err := s.Keys.GetActiveAPIKeyByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	if key.ID == 0 {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	if err := s.Keys.TouchAPIKey(key.ID); err != nil {
+		log.Println("Failed to record API key use for key ID:", key.ID, err) // Log error
+	}
+
+	return &auth.Principal{
+		Subject:  "api-key:" + strconv.FormatInt(key.ID, 10),
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
+// GetAPIKeysHandler handles GET requests listing API keys without their secrets
+func (h *Handler) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ManageAPIKeys) {
+		return
+	}
+
+	keys, err := h.apiKeys.GetAllAPIKeys()
+	if err != nil {
+		http.Error(w, "Failed to retrieve API keys", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "API keys retrieved successfully", keys)
+}
+
+// CreateAPIKeyHandler handles POST requests to issue a new API key. The
+// plaintext key is only ever returned in this response
+func (h *Handler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ManageAPIKeys) {
+		return
+	}
+
+	var requestBody struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(requestBody.Scopes) == 0 {
+		http.Error(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	for _, scope := range requestBody.Scopes {
+		if !policy.ValidScope(scope) {
+			http.Error(w, "Invalid scope: "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+
+	plaintext, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		http.Error(w, "Failed to generate API key", http.StatusInternalServerError)
+		return
+	}
+
+	key, err := h.apiKeys.CreateAPIKey(requestBody.Name, prefix, auth.HashAPIKey(plaintext), requestBody.Scopes)
+	if err != nil {
+		log.Println("Error creating API key:", err) // Log error
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, "API key created successfully", models.CreatedAPIKey{APIKey: key, Key: plaintext})
+}
+
+// RevokeAPIKeyHandler handles DELETE requests that revoke an API key
+func (h *Handler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ManageAPIKeys) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.apiKeys.RevokeAPIKey(id)
+	if err != nil {
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	if key.ID == 0 {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "API key revoked successfully", key)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"api-productnorder/auth"
+	"api-productnorder/policy"
+	"net/http"
+)
+
+// authorize consults the policy for the caller of the request, writing a 403
+// response and returning false when the action is denied. The principal is
+// nil when authentication is disabled
+func authorize(w http.ResponseWriter, r *http.Request, action policy.Action) (*auth.Principal, policy.Decision, bool) {
+	principal, _ := auth.FromContext(r.Context())
+	decision := policy.Decide(principal, action)
+	if !decision.Allowed() {
+		forbidden(w)
+		return principal, decision, false
+	}
+	return principal, decision, true
+}
+
+// authorizeAll is authorize for operations that cannot be narrowed to the
+// caller's own records, so an own-records-only grant is refused
+func authorizeAll(w http.ResponseWriter, r *http.Request, action policy.Action) bool {
+	_, decision, ok := authorize(w, r, action)
+	if ok && decision != policy.Allow {
+		forbidden(w)
+		return false
+	}
+	return ok
+}
+
+// ownsCustomer reports whether a caller with an own-records-only grant is the given customer
+func ownsCustomer(principal *auth.Principal, decision policy.Decision, customerID int64) bool {
+	return decision == policy.Allow || (principal != nil && principal.CustomerID == customerID)
+}
+
+// forbidden writes a 403 as plain text, like every other error response
+func forbidden(w http.ResponseWriter) {
+	http.Error(w, "Forbidden", http.StatusForbidden)
+}
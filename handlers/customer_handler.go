@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"api-productnorder/models"
+	"api-productnorder/policy"
+	"api-productnorder/repository"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type customerRequest struct {
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	Addresses []struct {
+		Label      string `json:"label"`
+		Line1      string `json:"line1"`
+		Line2      string `json:"line2"`
+		City       string `json:"city"`
+		Province   string `json:"province"`
+		PostalCode string `json:"postal_code"`
+		Country    string `json:"country"`
+	} `json:"addresses"`
+}
+
+// decodeCustomer reads and validates a customer request body, writing an
+// error response and returning false when it is invalid
+func decodeCustomer(w http.ResponseWriter, r *http.Request) (models.Customer, bool) {
+	var requestBody customerRequest
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return models.Customer{}, false
+	}
+
+	customer := models.Customer{
+		Name:      strings.TrimSpace(requestBody.Name),
+		Email:     strings.TrimSpace(requestBody.Email),
+		Phone:     strings.TrimSpace(requestBody.Phone),
+		Addresses: []models.Address{},
+	}
+	if customer.Name == "" || customer.Email == "" {
+		http.Error(w, "Name and email are required", http.StatusBadRequest)
+		return models.Customer{}, false
+	}
+	if _, err := mail.ParseAddress(customer.Email); err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return models.Customer{}, false
+	}
+
+	for _, a := range requestBody.Addresses {
+		address := models.Address{
+			Label:      strings.TrimSpace(a.Label),
+			Line1:      strings.TrimSpace(a.Line1),
+			Line2:      strings.TrimSpace(a.Line2),
+			City:       strings.TrimSpace(a.City),
+			Province:   strings.TrimSpace(a.Province),
+			PostalCode: strings.TrimSpace(a.PostalCode),
+			Country:    strings.TrimSpace(a.Country),
+		}
+		if address.Line1 == "" || address.City == "" || address.PostalCode == "" || address.Country == "" {
+			http.Error(w, "Addresses require line1, city, postal_code and country", http.StatusBadRequest)
+			return models.Customer{}, false
+		}
+		customer.Addresses = append(customer.Addresses, address)
+	}
+
+	return customer, true
+}
+
+// GetCustomersHandler handles GET requests listing all customers
+func (h *Handler) GetCustomersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ReadCustomers) {
+		return
+	}
+
+	customers, err := h.customers.GetAllCustomers()
+	if err != nil {
+		http.Error(w, "Failed to retrieve customers", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Customers retrieved successfully", customers)
+}
+
+// CreateCustomerHandler handles POST requests to create a new customer
+func (h *Handler) CreateCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.WriteCustomers) {
+		return
+	}
+
+	customer, ok := decodeCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	customer, err := h.customers.CreateCustomer(customer)
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		http.Error(w, "Email already in use", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println("Error creating customer:", err) // Log error
+		http.Error(w, "Failed to create customer", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, "Customer created successfully", customer)
+}
+
+// GetCustomerDetailHandler handles GET requests for a single customer by ID
+func (h *Handler) GetCustomerDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, decision, ok := authorize(w, r, policy.ReadCustomers)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	if !ownsCustomer(principal, decision, id) {
+		forbidden(w)
+		return
+	}
+
+	customer, err := h.customers.GetCustomerByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve customer", http.StatusInternalServerError)
+		return
+	}
+
+	if customer.ID == 0 {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Customer Detail", customer)
+}
+
+// UpdateCustomerHandler handles PUT requests to update a customer and their addresses
+func (h *Handler) UpdateCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.WriteCustomers) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	customer, ok := decodeCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	customer, err = h.customers.UpdateCustomer(id, customer)
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		http.Error(w, "Email already in use", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update customer", http.StatusInternalServerError)
+		return
+	}
+
+	if customer.ID == 0 {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Customer updated successfully", customer)
+}
+
+// DeleteCustomerHandler handles DELETE requests to delete a customer
+func (h *Handler) DeleteCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.DeleteCustomers) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.customers.DeleteCustomer(id)
+	if err != nil {
+		http.Error(w, "Failed to delete customer", http.StatusInternalServerError)
+		return
+	}
+
+	if customer.ID == 0 {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Customer deleted successfully", customer)
+}
+
+// GetCustomerOrdersHandler handles GET requests listing a customer's orders
+func (h *Handler) GetCustomerOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal, decision, ok := authorize(w, r, policy.ReadOrders)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	if !ownsCustomer(principal, decision, id) {
+		forbidden(w)
+		return
+	}
+
+	customer, err := h.customers.GetCustomerByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve customer", http.StatusInternalServerError)
+		return
+	}
+
+	if customer.ID == 0 {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	orders, err := h.orders.GetOrdersByCustomer(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve orders", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Order List", orders)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import "api-productnorder/repository"
+
+// Handler serves the API endpoints from the repositories it was built with
+type Handler struct {
+	products   repository.ProductRepository
+	orders     repository.OrderRepository
+	warehouses repository.WarehouseRepository
+	customers  repository.CustomerRepository
+	apiKeys    repository.APIKeyRepository
+	rates      repository.ExchangeRateRepository
+}
+
+// New returns a Handler backed by store
+func New(store repository.Store) *Handler {
+	return &Handler{
+		products:   store,
+		orders:     store,
+		warehouses: store,
+		customers:  store,
+		apiKeys:    store,
+		rates:      store,
+	}
+}
@@ -1,177 +1,373 @@
 package handlers
 
 import (
+	"api-productnorder/alerts"
 	"api-productnorder/config"
+	"api-productnorder/currency"
 	"api-productnorder/models"
+	"api-productnorder/policy"
 	"api-productnorder/repository"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
+
+	"github.com/gorilla/mux"
 )
 
-func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	db, err := config.ConnectDB()
-	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+	principal, decision, ok := authorize(w, r, policy.ReadOrders)
+	if !ok {
 		return
 	}
-	defer db.Close()
 
-	orders, err := repository.GetOrders(db)
+	// Customers only see their own orders.
+	var orders []models.Order
+	var err error
+	if decision == policy.Allow {
+		orders, err = h.orders.GetOrders()
+	} else {
+		orders, err = h.orders.GetOrdersByCustomer(principal.CustomerID)
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve orders", http.StatusInternalServerError)
 		return
 	}
 
-	response := models.ListOrder{
-		Data:    orders,
-		Message: "Order List",
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeResponse(w, r, http.StatusOK, "Order List", orders)
 }
 
-func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	db, err := config.ConnectDB()
-	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+	principal, decision, ok := authorize(w, r, policy.CreateOrders)
+	if !ok {
 		return
 	}
-	defer db.Close()
 
 	var requestBody struct {
 		Products []struct {
 			ID       int64 `json:"id"`
 			Quantity int64 `json:"quantity"`
 		} `json:"products"`
+		Allocation      models.AllocationRequest `json:"allocation"`
+		CustomerID      int64                    `json:"customer_id"`
+		ShippingAddress *models.OrderAddress     `json:"shipping_address"`
+		BillingAddress  *models.OrderAddress     `json:"billing_address"`
+		Currency        string                   `json:"currency"`
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	var orderProducts []models.Product
-	for _, productReq := range requestBody.Products {
-		product, err := repository.GetProductByID(db, productReq.ID)
-		if err != nil {
-			log.Println("Product not found:", productReq.ID) // Log error
-			http.Error(w, "Product not found", http.StatusNotFound)
-			return
-		}
+	if len(requestBody.Products) == 0 {
+		http.Error(w, "Order must contain at least one product", http.StatusBadRequest)
+		return
+	}
 
-		if product.Stock < productReq.Quantity {
-			log.Println("Product out of stock for product ID:", productReq.ID) // Log error
-			http.Error(w, "Product out of stock", http.StatusBadRequest)
+	// Customers always order for themselves.
+	if decision != policy.Allow {
+		if requestBody.CustomerID != 0 && requestBody.CustomerID != principal.CustomerID {
+			forbidden(w)
 			return
 		}
+		requestBody.CustomerID = principal.CustomerID
+	}
 
-		product.Stock -= productReq.Quantity
-		product.Sold += productReq.Quantity
+	if requestBody.Currency != "" && !currency.Valid(requestBody.Currency) {
+		http.Error(w, "Invalid currency", http.StatusBadRequest)
+		return
+	}
 
-		_, err = repository.UpdateProduct(db, product.ID, product.Name, product.Price, product.Stock)
-		if err != nil {
-			fmt.Println("Failed to update product stock for product ID:", product.ID) // Log error
-			http.Error(w, "Failed to update product stock", http.StatusInternalServerError)
-			return
-		}
+	allocation, ok := resolveAllocation(w, requestBody.Allocation)
+	if !ok {
+		return
+	}
 
-		orderProduct := models.Product{
-			ID:        product.ID,
-			Name:      product.Name,
-			Price:     product.Price,
-			Quantity:  productReq.Quantity,
-			Sold:      product.Sold,
-			Stock:     product.Stock,
-			CreatedAt: product.CreatedAt,
-			UpdatedAt: product.UpdatedAt,
-		}
+	if !validateOrderAddresses(w, requestBody.ShippingAddress, requestBody.BillingAddress) {
+		return
+	}
+	// Billing defaults to the shipping address when only one is given.
+	if requestBody.BillingAddress == nil && requestBody.ShippingAddress != nil {
+		billing := *requestBody.ShippingAddress
+		requestBody.BillingAddress = &billing
+	}
 
-		orderProducts = append(orderProducts, orderProduct)
+	newOrder := repository.NewOrder{
+		CustomerID:      requestBody.CustomerID,
+		Allocation:      allocation,
+		ShippingAddress: requestBody.ShippingAddress,
+		BillingAddress:  requestBody.BillingAddress,
+		Currency:        requestBody.Currency,
+	}
+	for _, productReq := range requestBody.Products {
+		if productReq.Quantity <= 0 {
+			http.Error(w, "Quantity must be greater than zero", http.StatusBadRequest)
+			return
+		}
+		newOrder.Items = append(newOrder.Items, repository.OrderItem{ProductID: productReq.ID, Quantity: productReq.Quantity})
 	}
 
-	orderID, err := repository.CreateOrder(db, orderProducts)
+	order, updatedProducts, err := h.orders.CreateOrder(newOrder)
+	if errors.Is(err, repository.ErrCustomerNotFound) {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrProductNotFound) {
+		log.Println(err) // Log error
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		log.Println("Product out of stock:", err) // Log error
+		http.Error(w, "Product out of stock", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrMixedCurrencies) {
+		http.Error(w, "Products in an order must share one currency", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrNoPriceInCurrency) {
+		log.Println(err) // Log error
+		http.Error(w, "Product has no price in the order currency", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		fmt.Println("Failed to create order:", err) // Log error
 		http.Error(w, "Failed to create order", http.StatusInternalServerError)
 		return
 	}
 
-	currentTime := time.Now().Format(time.RFC3339)
-	response := models.DetailOrder{
-		Data: models.Order{
-			ID:        &orderID,
-			Products:  orderProducts,
-			CreatedAt: &currentTime,
-			UpdatedAt: &currentTime,
-		},
-		Message: "Order created",
+	h.notifyLowStock(updatedProducts)
+
+	writeResponse(w, r, http.StatusOK, "Order created", order)
+}
+
+// resolveAllocation applies the default allocation rule and validates the
+// request, writing an error response and returning false when it is invalid
+func resolveAllocation(w http.ResponseWriter, allocation models.AllocationRequest) (models.AllocationRequest, bool) {
+	explicit := allocation.Rule != ""
+	if !explicit {
+		allocation.Rule = config.DefaultAllocationRule()
+	}
+	if !models.ValidAllocationRule(allocation.Rule) {
+		http.Error(w, "Invalid allocation rule", http.StatusBadRequest)
+		return models.AllocationRequest{}, false
+	}
+	// An explicitly requested nearest rule needs a destination; a configured
+	// default falls back to warehouse priority when none is given.
+	if explicit && allocation.Rule == models.AllocationNearest && (allocation.Latitude == nil || allocation.Longitude == nil) {
+		http.Error(w, "Latitude and longitude are required for nearest allocation", http.StatusBadRequest)
+		return models.AllocationRequest{}, false
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	return allocation, true
 }
 
-func GetOrderDetailHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// validateOrderAddresses trims the given addresses in place and checks their
+// required fields, writing an error response and returning false when one is
+// incomplete. Nil addresses are skipped
+func validateOrderAddresses(w http.ResponseWriter, addresses ...*models.OrderAddress) bool {
+	for _, address := range addresses {
+		if address == nil {
+			continue
+		}
+
+		for _, field := range []*string{&address.Name, &address.Phone, &address.Line1, &address.Line2, &address.City, &address.Province, &address.PostalCode, &address.Country} {
+			*field = strings.TrimSpace(*field)
+		}
+
+		if address.Name == "" || address.Line1 == "" || address.City == "" || address.PostalCode == "" || address.Country == "" {
+			http.Error(w, "Addresses require name, line1, city, postal_code and country", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateOrderAddressesHandler handles PUT requests that change the shipping
+// and/or billing address of a pending order
+func (h *Handler) UpdateOrderAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	idStr := r.URL.Path[len("/api/orders/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	if !authorizeAll(w, r, policy.WriteOrders) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		http.Error(w, "Invalid order ID", http.StatusBadRequest)
 		return
 	}
 
-	db, err := config.ConnectDB()
+	var requestBody struct {
+		ShippingAddress *models.OrderAddress `json:"shipping_address"`
+		BillingAddress  *models.OrderAddress `json:"billing_address"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&requestBody)
 	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.ShippingAddress == nil && requestBody.BillingAddress == nil {
+		http.Error(w, "Shipping or billing address is required", http.StatusBadRequest)
 		return
 	}
-	defer db.Close()
 
-	order, err := repository.GetOrderByID(db, id)
+	if !validateOrderAddresses(w, requestBody.ShippingAddress, requestBody.BillingAddress) {
+		return
+	}
+
+	err = h.orders.UpdateOrderAddresses(id, requestBody.ShippingAddress, requestBody.BillingAddress)
+	if errors.Is(err, repository.ErrOrderNotFound) {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrOrderNotPending) {
+		http.Error(w, "Only pending orders can be changed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println("Failed to update order addresses:", err) // Log error
+		http.Error(w, "Failed to update order", http.StatusInternalServerError)
+		return
+	}
+
+	order, err := h.orders.GetOrderByID(id)
 	if err != nil {
 		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
 		return
 	}
 
-	if order.ID == nil {
+	writeResponse(w, r, http.StatusOK, "Order updated successfully", order)
+}
+
+// UpdateOrderItemsHandler handles PUT requests that replace the line items of
+// a pending order, moving stock by the difference
+func (h *Handler) UpdateOrderItemsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.WriteOrders) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		Products []struct {
+			ID       int64 `json:"id"`
+			Quantity int64 `json:"quantity"`
+		} `json:"products"`
+		Allocation models.AllocationRequest `json:"allocation"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	allocation, ok := resolveAllocation(w, requestBody.Allocation)
+	if !ok {
+		return
+	}
+
+	var items []repository.OrderItem
+	var total int64
+	for _, productReq := range requestBody.Products {
+		if productReq.Quantity < 0 {
+			http.Error(w, "Quantity must not be negative", http.StatusBadRequest)
+			return
+		}
+		total += productReq.Quantity
+		items = append(items, repository.OrderItem{ProductID: productReq.ID, Quantity: productReq.Quantity})
+	}
+	if total == 0 {
+		http.Error(w, "Order must contain at least one product", http.StatusBadRequest)
+		return
+	}
+
+	updatedProducts, err := h.orders.UpdateOrderItems(id, items, allocation)
+	if errors.Is(err, repository.ErrOrderNotFound) {
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
+	if errors.Is(err, repository.ErrOrderNotPending) {
+		http.Error(w, "Only pending orders can be changed", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, repository.ErrProductNotFound) {
+		log.Println(err) // Log error
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		log.Println("Product out of stock:", err) // Log error
+		http.Error(w, "Product out of stock", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrMixedCurrencies) {
+		http.Error(w, "Products in an order must share one currency", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, repository.ErrNoPriceInCurrency) {
+		log.Println(err) // Log error
+		http.Error(w, "Product has no price in the order currency", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Println("Failed to update order items:", err) // Log error
+		http.Error(w, "Failed to update order", http.StatusInternalServerError)
+		return
+	}
+
+	h.notifyLowStock(updatedProducts)
 
-	response := models.DetailOrder{
-		Data:    order,
-		Message: "Order Detail",
+	order, err := h.orders.GetOrderByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeResponse(w, r, http.StatusOK, "Order updated successfully", order)
 }
 
-func DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
+func (h *Handler) GetOrderDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
+	principal, decision, ok := authorize(w, r, policy.ReadOrders)
+	if !ok {
+		return
+	}
+
 	idStr := r.URL.Path[len("/api/orders/"):]
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -179,29 +375,79 @@ func DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := config.ConnectDB()
+	order, err := h.orders.GetOrderByID(id)
 	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
 		return
 	}
-	defer db.Close()
 
-	order, err := repository.GetOrderByID(db, id)
-	if err != nil || (order.ID != nil && *order.ID == 0) {
+	if order.ID == nil {
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
 
-	err = repository.DeleteOrderByID(db, id)
+	if decision != policy.Allow && (order.CustomerID == nil || *order.CustomerID != principal.CustomerID) {
+		forbidden(w)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Order Detail", order)
+}
+
+func (h *Handler) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.DeleteOrders) {
+		return
+	}
+
+	idStr := r.URL.Path[len("/api/orders/"):]
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orders.GetOrderByID(id)
+	if err != nil || order.ID == nil {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	err = h.orders.DeleteOrderByID(id)
 	if err != nil {
 		http.Error(w, "Failed to delete order", http.StatusInternalServerError)
 		return
 	}
 
-	response := models.DeleteOrder{
-		Data:    order,
-		Message: "Order deleted successfully",
+	writeResponse(w, r, http.StatusOK, "Order deleted successfully", order)
+}
+
+// notifyLowStock emits a low-stock alert for every product the order pushed
+// below its reorder threshold, unless one was already sent since the last restock.
+func (h *Handler) notifyLowStock(products []models.Product) {
+	for _, product := range products {
+		if product.Stock >= product.ReorderThreshold {
+			continue
+		}
+
+		marked, err := h.products.MarkLowStockAlerted(product.ID)
+		if err != nil {
+			log.Println("Failed to record low stock alert for product ID:", product.ID, err) // Log error
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		alerts.Dispatch(alerts.LowStockEvent{
+			ProductID:        product.ID,
+			Name:             product.Name,
+			Stock:            product.Stock,
+			ReorderThreshold: product.ReorderThreshold,
+		})
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
 }
@@ -1,29 +1,29 @@
 package handlers
 
 import (
-	"api-productnorder/config"
+	"api-productnorder/currency"
 	"api-productnorder/models"
+	"api-productnorder/policy"
 	"api-productnorder/repository"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 )
 
-func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetProductsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	db, err := config.ConnectDB()
-	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+	if !authorizeAll(w, r, policy.ReadProducts) {
 		return
 	}
-	defer db.Close()
 
-	products, err := repository.GetAllProducts(db)
+	products, err := h.products.GetAllProducts()
 	if err != nil {
 		http.Error(w, "Failed to retrieve products", http.StatusInternalServerError)
 		return
@@ -34,78 +34,123 @@ func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := models.ApidogModel{
-		Data:    products,
-		Message: "Products retrieved successfully",
+	writeResponse(w, r, http.StatusOK, "Products retrieved successfully", products)
+}
+
+// GetLowStockProductsHandler handles GET requests listing products below their reorder threshold
+func (h *Handler) GetLowStockProductsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ReadInventory) {
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	products, err := h.products.GetLowStockProducts()
+	if err != nil {
+		http.Error(w, "Failed to retrieve products", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Low stock products retrieved successfully", products)
 }
 
 // CreateProductHandler handles POST requests to create a new product
-func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateProductHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	db, err := config.ConnectDB()
-	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+	if !authorizeAll(w, r, policy.WriteProducts) {
 		return
 	}
-	defer db.Close()
 
 	var requestBody struct {
-		Name  string `json:"name"`
-		Price int64  `json:"price"`
-		Stock int64  `json:"stock"`
+		Name             string                `json:"name"`
+		Price            int64                 `json:"price"`
+		Currency         string                `json:"currency"`
+		Prices           []models.ProductPrice `json:"prices"`
+		Stock            int64                 `json:"stock"`
+		ReorderThreshold int64                 `json:"reorder_threshold"`
+		StockPolicy      string                `json:"stock_policy"`
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	product, err := repository.CreateProduct(db, requestBody.Name, requestBody.Price, requestBody.Stock)
+	if requestBody.StockPolicy == "" {
+		requestBody.StockPolicy = models.StockPolicyReject
+	}
+	if !models.ValidStockPolicy(requestBody.StockPolicy) {
+		http.Error(w, "Invalid stock policy", http.StatusBadRequest)
+		return
+	}
+
+	currency, prices, ok := resolvePricing(w, requestBody.Currency, requestBody.Prices)
+	if !ok {
+		return
+	}
+
+	product, err := h.products.CreateProduct(requestBody.Name, requestBody.Price, currency, prices, requestBody.Stock, requestBody.ReorderThreshold, requestBody.StockPolicy)
 	if err != nil {
 		fmt.Printf("Error creating product: %v", err)
 		http.Error(w, "Failed to create product", http.StatusInternalServerError)
 		return
 	}
 
-	response := models.CreateProduct{
-		Data:    product,
-		Message: "Product created successfully",
+	writeResponse(w, r, http.StatusCreated, "Product created successfully", product)
+}
+
+// resolvePricing applies the default currency and validates a product's
+// price list, writing an error response and returning false when it is
+// invalid. The price list is returned in currency order.
+func resolvePricing(w http.ResponseWriter, code string, prices []models.ProductPrice) (string, []models.ProductPrice, bool) {
+	if code == "" {
+		code = currency.Default
+	}
+	if !currency.Valid(code) {
+		http.Error(w, "Invalid currency", http.StatusBadRequest)
+		return "", nil, false
+	}
+
+	seen := map[string]bool{code: true}
+	for _, price := range prices {
+		if !currency.Valid(price.Currency) || seen[price.Currency] || price.Price < 0 {
+			http.Error(w, "Invalid prices", http.StatusBadRequest)
+			return "", nil, false
+		}
+		seen[price.Currency] = true
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Currency < prices[j].Currency })
+
+	return code, prices, true
 }
 
 // GetProductDetailHandler handles GET requests for a single product by ID
-func GetProductDetailHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetProductDetailHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	idStr := r.URL.Path[len("/api/products/"):] // Get ID from URL
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+	if !authorizeAll(w, r, policy.ReadProducts) {
 		return
 	}
 
-	db, err := config.ConnectDB()
+	idStr := r.URL.Path[len("/api/products/"):] // Get ID from URL
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
 	}
-	defer db.Close()
 
-	product, err := repository.GetProductByID(db, id)
+	product, err := h.products.GetProductByID(id)
 	if err != nil {
 		http.Error(w, "Failed to retrieve product", http.StatusInternalServerError)
 		return
@@ -116,21 +161,20 @@ func GetProductDetailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := models.DetailProduct{
-		Data:    product,
-		Message: "Product Detail",
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeResponse(w, r, http.StatusOK, "Product Detail", product)
 }
 
 // UpdateProductHandler handles PUT requests to update a product
-func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
+	if !authorizeAll(w, r, policy.WriteProducts) {
+		return
+	}
+
 	idStr := r.URL.Path[len("/api/products/"):] // Get ID from URL
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -138,26 +182,69 @@ func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, err := config.ConnectDB()
+	var requestBody struct {
+		Name             string                 `json:"name"`
+		Price            int64                  `json:"price"`
+		Currency         *string                `json:"currency"`
+		Prices           *[]models.ProductPrice `json:"prices"`
+		Stock            int64                  `json:"stock"`
+		ReorderThreshold *int64                 `json:"reorder_threshold"`
+		StockPolicy      string                 `json:"stock_policy"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&requestBody)
 	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	defer db.Close()
 
-	var requestBody struct {
-		Name  string `json:"name"`
-		Price int64  `json:"price"`
-		Stock int64  `json:"stock"`
+	if requestBody.StockPolicy != "" && !models.ValidStockPolicy(requestBody.StockPolicy) {
+		http.Error(w, "Invalid stock policy", http.StatusBadRequest)
+		return
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	existing, err := h.products.GetProductByID(id)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		http.Error(w, "Failed to retrieve product", http.StatusInternalServerError)
 		return
 	}
+	if existing.ID == 0 {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+
+	// An omitted stock policy keeps the stored one, so a full-product PUT
+	// from an older client does not turn off backorders.
+	if requestBody.StockPolicy == "" {
+		requestBody.StockPolicy = existing.StockPolicy
+	}
 
-	product, err := repository.UpdateProduct(db, id, requestBody.Name, requestBody.Price, requestBody.Stock)
+	// Likewise an omitted reorder threshold keeps the stored one instead of
+	// turning low stock alerts off.
+	reorderThreshold := existing.ReorderThreshold
+	if requestBody.ReorderThreshold != nil {
+		reorderThreshold = *requestBody.ReorderThreshold
+	}
+
+	// Clients that predate currencies leave both fields out; the product
+	// keeps its currency and price list rather than falling back to IDR.
+	code, prices := existing.Currency, existing.Prices
+	if requestBody.Currency != nil {
+		code = *requestBody.Currency
+	}
+	if requestBody.Prices != nil {
+		prices = *requestBody.Prices
+	}
+	currency, prices, ok := resolvePricing(w, code, prices)
+	if !ok {
+		return
+	}
+
+	product, err := h.products.UpdateProduct(id, requestBody.Name, requestBody.Price, currency, prices, requestBody.Stock, reorderThreshold, requestBody.StockPolicy)
+	if errors.Is(err, repository.ErrStockBelowWarehouses) {
+		http.Error(w, "Stock cannot be less than the stock held by warehouses", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update product", http.StatusInternalServerError)
 		return
@@ -168,36 +255,28 @@ func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := models.DetailProduct{
-		Data:    product,
-		Message: "Product updated successfully",
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeResponse(w, r, http.StatusOK, "Product updated successfully", product)
 }
 
 // DeleteProductHandler handles DELETE requests to delete a product
-func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	idStr := r.URL.Path[len("/api/products/"):] // Get ID from URL
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+	if !authorizeAll(w, r, policy.DeleteProducts) {
 		return
 	}
 
-	db, err := config.ConnectDB()
+	idStr := r.URL.Path[len("/api/products/"):] // Get ID from URL
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "Failed to connect to database", http.StatusInternalServerError)
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
 	}
-	defer db.Close()
 
-	product, err := repository.DeleteProduct(db, id)
+	product, err := h.products.DeleteProduct(id)
 	if err != nil {
 		http.Error(w, "Failed to delete product", http.StatusInternalServerError)
 		return
@@ -208,14 +287,5 @@ func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := struct {
-		Message string      `json:"message"`
-		Data    models.Data `json:"data"`
-	}{
-		Message: "Product deleted successfully",
-		Data:    product,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeResponse(w, r, http.StatusOK, "Product deleted successfully", product)
 }
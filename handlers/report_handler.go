@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"api-productnorder/currency"
+	"api-productnorder/models"
+	"api-productnorder/policy"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// GetExchangeRatesHandler handles GET requests listing the exchange rates
+// reports convert with
+func (h *Handler) GetExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ReadReports) {
+		return
+	}
+
+	rates, err := h.rates.GetExchangeRates()
+	if err != nil {
+		http.Error(w, "Failed to retrieve exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Exchange rates retrieved successfully", rates)
+}
+
+// SetExchangeRateHandler handles PUT requests that set the value of one unit
+// of a currency in the default currency
+func (h *Handler) SetExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ManageRates) {
+		return
+	}
+
+	code := mux.Vars(r)["currency"]
+	if !currency.Valid(code) {
+		http.Error(w, "Invalid currency", http.StatusBadRequest)
+		return
+	}
+	// The default currency is what every rate is quoted against.
+	if code == currency.Default {
+		http.Error(w, "The default currency has a fixed rate of 1", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		Rate float64 `json:"rate"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.Rate <= 0 {
+		http.Error(w, "Rate must be greater than zero", http.StatusBadRequest)
+		return
+	}
+
+	rate, err := h.rates.SetExchangeRate(code, requestBody.Rate)
+	if err != nil {
+		log.Println("Failed to set exchange rate:", err) // Log error
+		http.Error(w, "Failed to set exchange rate", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Exchange rate updated successfully", rate)
+}
+
+// GetSalesReportHandler handles GET requests totalling sales by order
+// currency and converted into the currency named by the currency query
+// parameter, the default currency if none is given
+func (h *Handler) GetSalesReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ReadReports) {
+		return
+	}
+
+	code := r.URL.Query().Get("currency")
+	if code == "" {
+		code = currency.Default
+	}
+	if !currency.Valid(code) {
+		http.Error(w, "Invalid currency", http.StatusBadRequest)
+		return
+	}
+
+	stored, err := h.rates.GetExchangeRates()
+	if err != nil {
+		http.Error(w, "Failed to retrieve exchange rates", http.StatusInternalServerError)
+		return
+	}
+	rates := make(currency.Rates, len(stored))
+	for _, rate := range stored {
+		rates[rate.Currency] = rate.Rate
+	}
+
+	totals, err := h.orders.GetSalesByCurrency()
+	if err != nil {
+		http.Error(w, "Failed to retrieve sales", http.StatusInternalServerError)
+		return
+	}
+
+	report := models.SalesReport{Currency: code, Currencies: totals}
+	for i := range report.Currencies {
+		total := &report.Currencies[i]
+		total.Converted, err = rates.Convert(total.Total, total.Currency, code)
+		if errors.Is(err, currency.ErrNoRate) {
+			http.Error(w, "Missing exchange rate: "+err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to convert sales", http.StatusInternalServerError)
+			return
+		}
+		report.Total += total.Converted
+	}
+
+	writeResponse(w, r, http.StatusOK, "Sales report", report)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"api-productnorder/apiversion"
+	"api-productnorder/models"
+	"encoding/json"
+	"net/http"
+)
+
+// writeResponse writes data in the envelope every successful response
+// shares. Version 1 requests get orders in the shape version 1 has always
+// used; later versions get order lines and the version in the envelope.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, message string, data interface{}) {
+	response := models.Response{Data: data, Message: message}
+	if version := apiversion.FromContext(r.Context()); version == apiversion.V1 {
+		switch d := data.(type) {
+		case models.Order:
+			response.Data = d.Legacy()
+		case []models.Order:
+			response.Data = models.LegacyOrders(d)
+		}
+	} else {
+		response.APIVersion = version
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
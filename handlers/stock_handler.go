@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"api-productnorder/models"
+	"api-productnorder/policy"
+	"api-productnorder/repository"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// AdjustProductStockHandler handles POST requests that change a product's
+// stock by a relative amount without touching its name or price
+func (h *Handler) AdjustProductStockHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.WriteProducts) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		Delta       int64  `json:"delta"`
+		Reason      string `json:"reason"`
+		WarehouseID int64  `json:"warehouse_id"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.Delta == 0 {
+		http.Error(w, "Delta must not be zero", http.StatusBadRequest)
+		return
+	}
+
+	requestBody.Reason = strings.TrimSpace(requestBody.Reason)
+	if requestBody.Reason == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	adjustment, product, err := h.products.AdjustStock(id, requestBody.WarehouseID, requestBody.Delta, requestBody.Reason)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrWarehouseNotFound) {
+		http.Error(w, "Warehouse not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		http.Error(w, "Stock cannot go below zero", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println("Failed to adjust stock for product ID:", id, err) // Log error
+		http.Error(w, "Failed to adjust stock", http.StatusInternalServerError)
+		return
+	}
+
+	result := models.StockAdjustmentResult{
+		Adjustment: adjustment,
+		Product:    product,
+	}
+	writeResponse(w, r, http.StatusCreated, "Stock adjusted successfully", result)
+}
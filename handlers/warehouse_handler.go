@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"api-productnorder/models"
+	"api-productnorder/policy"
+	"api-productnorder/repository"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type warehouseRequest struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Priority  int64   `json:"priority"`
+}
+
+// decodeWarehouse reads and validates a warehouse request body, writing an
+// error response and returning false when it is invalid
+func decodeWarehouse(w http.ResponseWriter, r *http.Request) (models.Warehouse, bool) {
+	var requestBody warehouseRequest
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return models.Warehouse{}, false
+	}
+
+	requestBody.Code = strings.TrimSpace(requestBody.Code)
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Code == "" || requestBody.Name == "" {
+		http.Error(w, "Code and name are required", http.StatusBadRequest)
+		return models.Warehouse{}, false
+	}
+
+	if requestBody.Latitude < -90 || requestBody.Latitude > 90 || requestBody.Longitude < -180 || requestBody.Longitude > 180 {
+		http.Error(w, "Invalid coordinates", http.StatusBadRequest)
+		return models.Warehouse{}, false
+	}
+
+	return models.Warehouse{
+		Code:      requestBody.Code,
+		Name:      requestBody.Name,
+		Latitude:  requestBody.Latitude,
+		Longitude: requestBody.Longitude,
+		Priority:  requestBody.Priority,
+	}, true
+}
+
+// GetWarehousesHandler handles GET requests listing all warehouses
+func (h *Handler) GetWarehousesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ReadWarehouses) {
+		return
+	}
+
+	warehouses, err := h.warehouses.GetAllWarehouses()
+	if err != nil {
+		http.Error(w, "Failed to retrieve warehouses", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Warehouses retrieved successfully", warehouses)
+}
+
+// CreateWarehouseHandler handles POST requests to create a new warehouse
+func (h *Handler) CreateWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.WriteWarehouses) {
+		return
+	}
+
+	warehouse, ok := decodeWarehouse(w, r)
+	if !ok {
+		return
+	}
+
+	warehouse, err := h.warehouses.CreateWarehouse(warehouse)
+	if err != nil {
+		log.Println("Error creating warehouse:", err) // Log error
+		http.Error(w, "Failed to create warehouse", http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, r, http.StatusCreated, "Warehouse created successfully", warehouse)
+}
+
+// GetWarehouseDetailHandler handles GET requests for a single warehouse by ID
+func (h *Handler) GetWarehouseDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.ReadWarehouses) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	warehouse, err := h.warehouses.GetWarehouseByID(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve warehouse", http.StatusInternalServerError)
+		return
+	}
+
+	if warehouse.ID == 0 {
+		http.Error(w, "Warehouse not found", http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Warehouse Detail", warehouse)
+}
+
+// UpdateWarehouseHandler handles PUT requests to update a warehouse
+func (h *Handler) UpdateWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.WriteWarehouses) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	warehouse, ok := decodeWarehouse(w, r)
+	if !ok {
+		return
+	}
+
+	warehouse, err = h.warehouses.UpdateWarehouse(id, warehouse)
+	if err != nil {
+		http.Error(w, "Failed to update warehouse", http.StatusInternalServerError)
+		return
+	}
+
+	if warehouse.ID == 0 {
+		http.Error(w, "Warehouse not found", http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Warehouse updated successfully", warehouse)
+}
+
+// DeleteWarehouseHandler handles DELETE requests to delete an empty warehouse
+func (h *Handler) DeleteWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorizeAll(w, r, policy.DeleteWarehouses) {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	warehouse, err := h.warehouses.DeleteWarehouse(id)
+	if errors.Is(err, repository.ErrWarehouseNotEmpty) {
+		http.Error(w, "Warehouse still holds stock", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete warehouse", http.StatusInternalServerError)
+		return
+	}
+
+	if warehouse.ID == 0 {
+		http.Error(w, "Warehouse not found", http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, "Warehouse deleted successfully", warehouse)
+}
@@ -0,0 +1,105 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds each dependency check so a hung dependency cannot hang
+// the probe.
+const checkTimeout = 2 * time.Second
+
+// CheckFunc reports whether a dependency is usable.
+type CheckFunc func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs the readiness checks. It is safe for concurrent use.
+type Checker struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// New returns a Checker without any checks.
+func New() *Checker {
+	return &Checker{}
+}
+
+// Add registers a readiness check under name.
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// SetDraining makes readiness fail from now on, so the orchestrator stops
+// routing traffic to a server that is shutting down.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// CheckResult is the outcome of one dependency check. The probe is public,
+// so errors are logged rather than reported.
+type CheckResult struct {
+	Status string `json:"status"`
+}
+
+// Report is the body of a probe response.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// LiveHandler answers 200 as long as the process can serve requests.
+func (c *Checker) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: "ok"})
+}
+
+// ReadyHandler runs every check and answers 200 when all pass, or 503 with
+// the failing checks when any fails or the server is draining.
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if c.draining.Load() {
+		writeReport(w, http.StatusServiceUnavailable, Report{Status: "draining"})
+		return
+	}
+
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	report := Report{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+	status := http.StatusOK
+	for _, nc := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		err := nc.check(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("Readiness check %s failed: %v", nc.name, err)
+			report.Checks[nc.name] = CheckResult{Status: "fail"}
+			report.Status = "fail"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		report.Checks[nc.name] = CheckResult{Status: "ok"}
+	}
+
+	writeReport(w, status, report)
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,41 @@
+// Package idempotency lets clients retry POST requests safely by replaying
+// the stored response of the first attempt.
+package idempotency
+
+import (
+	"net/http"
+	"time"
+)
+
+// Header is the request header carrying the client's idempotency key.
+const Header = "Idempotency-Key"
+
+// maxKeyLength bounds the keys clients may send.
+const maxKeyLength = 255
+
+// Record is what a store keeps for one idempotency key.
+type Record struct {
+	// RequestHash fingerprints the request that first used the key.
+	RequestHash string
+	// Completed is false while the first request is still being handled.
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+	ExpiresAt time.Time
+}
+
+// Store keeps idempotency records. Implementations must be safe for
+// concurrent use and must make Begin atomic, so that only one of several
+// concurrent requests with the same key is started.
+type Store interface {
+	// Begin reserves key for a request with the given hash until expiresAt.
+	// If the key is already reserved and not expired, Begin returns the
+	// existing record and started is false.
+	Begin(key, requestHash string, expiresAt time.Time, now time.Time) (existing Record, started bool, err error)
+	// Complete stores the response for a key reserved by Begin.
+	Complete(key string, record Record) error
+	// Release drops a reservation so the request can be retried, used when
+	// the first attempt failed without a response worth replaying.
+	Release(key string) error
+}
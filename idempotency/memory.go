@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many Begin calls pass between sweeps of expired records.
+const sweepEvery = 256
+
+// MemoryStore keeps records in process memory. Keys are only honoured by the
+// instance that saw the first request.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	calls   int
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record)}
+}
+
+func (s *MemoryStore) Begin(key, requestHash string, expiresAt time.Time, now time.Time) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		for k, r := range s.records {
+			if !now.Before(r.ExpiresAt) {
+				delete(s.records, k)
+			}
+		}
+	}
+
+	if existing, ok := s.records[key]; ok && now.Before(existing.ExpiresAt) {
+		return existing, false, nil
+	}
+
+	s.records[key] = Record{RequestHash: requestHash, ExpiresAt: expiresAt}
+	return Record{}, true, nil
+}
+
+func (s *MemoryStore) Complete(key string, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	record.RequestHash = existing.RequestHash
+	record.ExpiresAt = existing.ExpiresAt
+	record.Completed = true
+	s.records[key] = record
+	return nil
+}
+
+func (s *MemoryStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
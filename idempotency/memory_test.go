@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	start := time.Now()
+	ttl := time.Minute
+
+	tests := []struct {
+		name        string
+		key, hash   string
+		after       time.Duration
+		wantStarted bool
+		wantRecord  Record
+	}{
+		{name: "first use", key: "a", hash: "h1", wantStarted: true},
+		{name: "in flight", key: "a", hash: "h1", after: time.Second, wantRecord: Record{RequestHash: "h1", ExpiresAt: start.Add(ttl)}},
+		{name: "other key", key: "b", hash: "h1", after: time.Second, wantStarted: true},
+		{name: "just before expiry", key: "a", hash: "h2", after: ttl - time.Nanosecond, wantRecord: Record{RequestHash: "h1", ExpiresAt: start.Add(ttl)}},
+		{name: "expired", key: "a", hash: "h2", after: ttl, wantStarted: true},
+	}
+
+	for _, tt := range tests {
+		now := start.Add(tt.after)
+		record, started, err := s.Begin(tt.key, tt.hash, now.Add(ttl), now)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if started != tt.wantStarted {
+			t.Errorf("%s: got started %v, want %v", tt.name, started, tt.wantStarted)
+		}
+		if record.RequestHash != tt.wantRecord.RequestHash || !record.ExpiresAt.Equal(tt.wantRecord.ExpiresAt) || record.Completed {
+			t.Errorf("%s: got %+v, want %+v", tt.name, record, tt.wantRecord)
+		}
+	}
+}
+
+func TestMemoryStoreCompleteAndRelease(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	expiresAt := now.Add(time.Minute)
+
+	s.Begin("a", "h1", expiresAt, now)
+	// Complete keeps the hash and expiry Begin reserved the key with.
+	s.Complete("a", Record{RequestHash: "other", Status: 201, Body: []byte("done"), ExpiresAt: now.Add(time.Hour)})
+	record, started, _ := s.Begin("a", "h1", expiresAt, now)
+	if started || !record.Completed || record.Status != 201 || string(record.Body) != "done" || record.RequestHash != "h1" || !record.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("after Complete: got %+v, started %v", record, started)
+	}
+
+	// Completing a key that was never reserved, or has been released, stores
+	// nothing.
+	s.Complete("b", Record{Status: 201})
+	if _, started, _ := s.Begin("b", "h1", expiresAt, now); !started {
+		t.Error("Complete reserved an unknown key")
+	}
+
+	s.Release("a")
+	if _, started, _ := s.Begin("a", "h2", expiresAt, now); !started {
+		t.Error("released key is still reserved")
+	}
+}
+
+// TestMemoryStoreSweep checks that expired records are dropped every
+// sweepEvery calls to Begin.
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore()
+	start := time.Now()
+
+	s.Begin("expired", "h", start.Add(time.Minute), start)
+	s.Begin("live", "h", start.Add(time.Hour), start)
+	for i := 2; i < sweepEvery; i++ {
+		s.Begin("busy", "h", start.Add(2*time.Minute), start.Add(time.Minute))
+	}
+
+	if _, ok := s.records["expired"]; ok {
+		t.Error("expired record was not swept")
+	}
+	for _, key := range []string{"live", "busy"} {
+		if _, ok := s.records[key]; !ok {
+			t.Errorf("record %q was swept before expiring", key)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package idempotency
+
+import (
+	"api-productnorder/apiversion"
+	"api-productnorder/auth"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware honours the Idempotency-Key header on POST requests. The first
+// request with a key is handled normally and its response stored for ttl;
+// a retry with the same key and the same method, path, body and API version
+// gets the stored response back with an Idempotent-Replayed header. Reusing a key for
+// a different request is rejected with 422, and a retry that arrives while
+// the first request is still running gets 409. Responses with a 5xx status
+// are not stored, so those requests can be retried. Keys are scoped to the
+// caller, so the middleware must run after the auth middleware.
+func Middleware(store Store, ttl time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxKeyLength {
+				http.Error(w, "Idempotency key is too long", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := auth.ClientKey(r) + "|" + key
+			hash := requestHash(r, body)
+			now := time.Now()
+
+			existing, started, err := store.Begin(storeKey, hash, now.Add(ttl), now)
+			if err != nil {
+				log.Println("Idempotency store failed:", err) // Log error
+				http.Error(w, "Failed to process idempotency key", http.StatusInternalServerError)
+				return
+			}
+
+			if !started {
+				switch {
+				case existing.RequestHash != hash:
+					http.Error(w, "Idempotency key was already used for a different request", http.StatusUnprocessableEntity)
+				case !existing.Completed:
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, "A request with this idempotency key is still being processed", http.StatusConflict)
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				// The handler panicked; free the key so the client can retry.
+				if err := store.Release(storeKey); err != nil {
+					log.Println("Failed to release idempotency key:", err) // Log error
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+			completed = true
+
+			if rec.status >= http.StatusInternalServerError {
+				err = store.Release(storeKey)
+			} else {
+				err = store.Complete(storeKey, Record{
+					Status: rec.status,
+					Header: rec.Header().Clone(),
+					Body:   rec.body.Bytes(),
+				})
+			}
+			if err != nil {
+				log.Println("Failed to store idempotent response:", err) // Log error
+			}
+		})
+	}
+}
+
+// requestHash fingerprints the parts of a request that must match on retry.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method)
+	h.Write([]byte{0})
+	io.WriteString(h, r.URL.Path)
+	h.Write([]byte{0})
+	h.Write(body)
+	// The response format depends on the API version, so a retry asking for
+	// another version is a different request. Requests that do not ask for
+	// one hash as they always have.
+	if version := r.Header.Get(apiversion.Header); version != "" {
+		h.Write([]byte{0})
+		io.WriteString(h, version)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a stored response. Headers already set by outer middleware
+// for the current request, such as rate limit counters, are kept.
+func replay(w http.ResponseWriter, record Record) {
+	for name, values := range record.Header {
+		if _, ok := w.Header()[name]; ok {
+			continue
+		}
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// recorder passes a response through to the client while keeping a copy.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,186 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"api-productnorder/apiversion"
+)
+
+// countingHandler answers 201 with the number of calls so far, 500 for a body of
+// "fail", and panics for a body of "panic".
+type countingHandler struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.calls++
+	calls := h.calls
+	h.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	switch string(body) {
+	case "fail":
+		http.Error(w, "boom", http.StatusInternalServerError)
+	case "panic":
+		panic("boom")
+	default:
+		w.Header().Set("X-Call", strconv.Itoa(calls))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created " + strconv.Itoa(calls)))
+	}
+}
+
+// serve sends a request through h, turning a panic into a 500 as net/http
+// would.
+func serve(h http.Handler, req *http.Request) (rec *httptest.ResponseRecorder) {
+	rec = httptest.NewRecorder()
+	defer func() {
+		if recover() != nil {
+			rec.Code = http.StatusInternalServerError
+		}
+	}()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := &countingHandler{}
+	h := Middleware(NewMemoryStore(), time.Hour)(handler)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		version  string
+		body     string
+		status   int
+		want     string
+		replayed bool
+		calls    int
+	}{
+		{name: "first use", key: "k1", body: "a", status: http.StatusCreated, want: "created 1", calls: 1},
+		{name: "identical retry", key: "k1", body: "a", status: http.StatusCreated, want: "created 1", replayed: true, calls: 1},
+		{name: "changed body", key: "k1", body: "b", status: http.StatusUnprocessableEntity, want: "different request", calls: 1},
+		{name: "changed path", key: "k1", path: "/other", body: "a", status: http.StatusUnprocessableEntity, want: "different request", calls: 1},
+		{name: "changed version", key: "k1", version: "2", body: "a", status: http.StatusUnprocessableEntity, want: "different request", calls: 1},
+		{name: "other key", key: "k2", body: "a", status: http.StatusCreated, want: "created 2", calls: 2},
+		{name: "no key", body: "a", status: http.StatusCreated, want: "created 3", calls: 3},
+		{name: "not a POST", method: "PUT", key: "k1", body: "a", status: http.StatusCreated, want: "created 4", calls: 4},
+		{name: "key too long", key: strings.Repeat("k", maxKeyLength+1), body: "a", status: http.StatusBadRequest, want: "too long", calls: 4},
+		{name: "server error", key: "k3", body: "fail", status: http.StatusInternalServerError, want: "boom", calls: 5},
+		{name: "retry after server error", key: "k3", body: "fail", status: http.StatusInternalServerError, want: "boom", calls: 6},
+		{name: "panic", key: "k4", body: "panic", status: http.StatusInternalServerError, calls: 7},
+		{name: "retry after panic", key: "k4", body: "a", status: http.StatusCreated, want: "created 8", calls: 8},
+	}
+
+	for _, tt := range tests {
+		method, path := tt.method, tt.path
+		if method == "" {
+			method = http.MethodPost
+		}
+		if path == "" {
+			path = "/orders"
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(tt.body))
+		if tt.key != "" {
+			req.Header.Set(Header, tt.key)
+		}
+		if tt.version != "" {
+			req.Header.Set(apiversion.Header, tt.version)
+		}
+
+		rec := serve(h, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: got body %q, want %q", tt.name, rec.Body.String(), tt.want)
+		}
+		if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.replayed {
+			t.Errorf("%s: got replayed %v, want %v", tt.name, replayed, tt.replayed)
+		}
+		if handler.calls != tt.calls {
+			t.Errorf("%s: handler called %d times, want %d", tt.name, handler.calls, tt.calls)
+		}
+	}
+}
+
+func TestMiddlewareReplaysHeaders(t *testing.T) {
+	h := Middleware(NewMemoryStore(), time.Hour)(&countingHandler{})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("a"))
+		req.Header.Set(Header, "k")
+		rec := httptest.NewRecorder()
+		// Outer middleware, such as the rate limiter, sets its own headers
+		// for the current request.
+		rec.Header().Set("RateLimit-Remaining", "9")
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	send()
+	rec := send()
+	if got := rec.Header().Get("X-Call"); got != "1" {
+		t.Errorf("got stored header X-Call %q, want %q", got, "1")
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "9" {
+		t.Errorf("replay replaced RateLimit-Remaining with %q", got)
+	}
+}
+
+// TestMiddlewareInFlight retries a request while the first attempt is still
+// being handled.
+func TestMiddlewareInFlight(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	h := Middleware(NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("a"))
+		req.Header.Set(Header, "k")
+		return req
+	}
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest())
+		done <- rec.Code
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusConflict {
+		t.Errorf("retry in flight: got %d, want 409", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("retry in flight: got Retry-After %q, want 1", got)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("first request: got %d, want 201", code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after completion: got %d, replayed %q", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+}
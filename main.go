@@ -1,28 +1,200 @@
 package main
 
 import (
+	"api-productnorder/alerts"
+	"api-productnorder/auth"
+	"api-productnorder/config"
 	"api-productnorder/handlers"
+	"api-productnorder/health"
+	"api-productnorder/idempotency"
+	"api-productnorder/ratelimit"
+	"api-productnorder/repository"
+	"api-productnorder/router"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-
-	"github.com/gorilla/mux"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
-	r := mux.NewRouter()
-	r.HandleFunc("/api/products", handlers.GetProductsHandler).Methods("GET")
-	r.HandleFunc("/api/products", handlers.CreateProductHandler).Methods("POST")
-	r.HandleFunc("/api/products/{id:[0-9]+}", handlers.GetProductDetailHandler).Methods("GET")
-	r.HandleFunc("/api/products/{id:[0-9]+}", handlers.UpdateProductHandler).Methods("PUT")
-	r.HandleFunc("/api/products/{id:[0-9]+}", handlers.DeleteProductHandler).Methods("DELETE")
-
-	r.HandleFunc("/api/orders", handlers.GetOrdersHandler).Methods("GET")
-	r.HandleFunc("/api/orders", handlers.CreateOrderHandler).Methods("POST")
-	r.HandleFunc("/api/orders/{id}", handlers.GetOrderDetailHandler).Methods("GET")
-	r.HandleFunc("/api/orders/{id}", handlers.DeleteOrderHandler).Methods("DELETE")
-
-	http.Handle("/", r)
-	fmt.Println("Terhubung ke server")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	if config.AutoMigrate() {
+		m, err := newMigrator()
+		if err != nil {
+			log.Fatal("Failed to connect to database for migrations: ", err)
+		}
+		ran, err := m.Up()
+		for _, migration := range ran {
+			log.Printf("Applied migration %04d %s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to migrate database: ", err)
+		}
+	}
+
+	if url := config.AlertWebhookURL(); url != "" {
+		alerts.SetSink(alerts.NewWebhookSink(url))
+	}
+
+	db, err := config.ConnectDB()
+	if err != nil {
+		log.Fatal("Failed to connect to database: ", err)
+	}
+	driver, err := config.DBDriver()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dialect := repository.MySQL
+	switch driver {
+	case config.DriverSQLite:
+		dialect = repository.SQLite
+	case config.DriverPostgres:
+		dialect = repository.Postgres
+	}
+	store := repository.NewSQL(db, dialect)
+
+	routerConfig := router.Config{Store: store}
+	if config.AuthDisabled() {
+		log.Println("Authentication is disabled (AUTH_DISABLED=true)")
+	} else {
+		// API keys are always accepted; bearer tokens only once a JWT key is
+		// configured, so machine clients can run without one.
+		routerConfig.APIKeys = handlers.APIKeyStore{Keys: store}
+
+		authConfig, err := config.AuthConfig()
+		if err != nil {
+			log.Fatal("Failed to load authentication keys: ", err)
+		}
+		validator, err := auth.NewValidator(authConfig)
+		switch {
+		case errors.Is(err, auth.ErrNoKeys):
+			// Keys are issued through the admin API, which needs a bearer
+			// token, so without one an empty key table locks everyone out.
+			active, err := hasActiveAPIKey(store)
+			if err != nil {
+				log.Fatal("Failed to load API keys: ", err)
+			}
+			if !active {
+				log.Fatal("No authentication method is configured: set a JWT key, or AUTH_DISABLED=true to run without authentication")
+			}
+			log.Println("No JWT key configured; only API keys are accepted")
+		case err != nil:
+			log.Fatal(err)
+		default:
+			routerConfig.Auth = validator
+		}
+	}
+
+	if !config.RateLimitDisabled() {
+		limits, err := config.RateLimits(router.DefaultRateLimits, router.RouteNames())
+		if err != nil {
+			log.Fatal("Failed to load rate limits: ", err)
+		}
+		routerConfig.RateLimitStore = ratelimit.NewMemoryStore()
+		routerConfig.RateLimits = limits
+	}
+
+	idempotencyTTL, err := config.IdempotencyTTL()
+	if err != nil {
+		log.Fatal("Invalid IDEMPOTENCY_TTL: ", err)
+	}
+	routerConfig.IdempotencyStore = idempotency.NewMemoryStore()
+	routerConfig.IdempotencyTTL = idempotencyTTL
+
+	checker := health.New()
+	checker.Add("database", func(ctx context.Context) error {
+		db, err := config.ConnectDB()
+		if err != nil {
+			return err
+		}
+		return db.PingContext(ctx)
+	})
+	checker.Add("migrations", func(ctx context.Context) error {
+		m, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		return m.Check(ctx)
+	})
+	routerConfig.Health = checker
+
+	serverConfig, err := config.ServerConfig()
+	if err != nil {
+		log.Fatal("Invalid server configuration: ", err)
+	}
+
+	server := &http.Server{
+		Addr:              serverConfig.Addr,
+		Handler:           router.New(routerConfig),
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout,
+		ReadTimeout:       serverConfig.ReadTimeout,
+		WriteTimeout:      serverConfig.WriteTimeout,
+		IdleTimeout:       serverConfig.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Println("Terhubung ke server")
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	checker.SetDraining()
+	if serverConfig.DrainDelay > 0 {
+		log.Println("Draining, readiness now fails; waiting", serverConfig.DrainDelay)
+		time.Sleep(serverConfig.DrainDelay)
+	}
+
+	// Stop accepting connections and let in-flight requests, such as order
+	// transactions, finish before the database pool is closed.
+	log.Println("Shutting down, waiting up to", serverConfig.ShutdownTimeout, "for in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Graceful shutdown failed:", err) // Log error
+		server.Close()
+	}
+
+	// With no requests left to trigger them, send the alerts still on their
+	// way to the webhook within what is left of the shutdown timeout.
+	if err := alerts.Drain(shutdownCtx); err != nil {
+		log.Println("Dropping low stock alerts still being sent:", err) // Log error
+	}
+
+	if err := config.CloseDB(); err != nil {
+		log.Println("Failed to close database:", err) // Log error
+	}
+	log.Println("Server stopped")
+}
+
+// hasActiveAPIKey reports whether the store holds an API key that has not
+// been revoked.
+func hasActiveAPIKey(keys repository.APIKeyRepository) (bool, error) {
+	all, err := keys.GetAllAPIKeys()
+	if err != nil {
+		return false, err
+	}
+	for _, key := range all {
+		if key.RevokedAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
 }
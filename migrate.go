@@ -0,0 +1,124 @@
+package main
+
+import (
+	"api-productnorder/config"
+	"api-productnorder/migrations"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: api-productnorder migrate <command>
+
+commands:
+  up              apply every pending migration
+  down [n]        roll back the last n migrations (default 1)
+  status          list migrations and whether they are applied
+  to <version>    migrate up or down to version (0 rolls back everything)`
+
+// newMigrator returns a migrator for the configured database.
+func newMigrator() (*migrations.Migrator, error) {
+	db, err := config.ConnectDB()
+	if err != nil {
+		return nil, err
+	}
+	driver, err := config.DBDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	load := migrations.MySQL
+	switch driver {
+	case config.DriverSQLite:
+		load = migrations.SQLite
+	case config.DriverPostgres:
+		load = migrations.Postgres
+	}
+	all, err := load()
+	if err != nil {
+		return nil, err
+	}
+	m := migrations.New(db, all)
+	m.Postgres = driver == config.DriverPostgres
+	return m, nil
+}
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	defer config.CloseDB()
+
+	m, err := newMigrator()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to connect to database:", err)
+		return 1
+	}
+
+	var ran []migrations.Migration
+	switch args[0] {
+	case "up":
+		ran, err = m.Up()
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			n, err = strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "down takes a positive number of migrations")
+				return 2
+			}
+		}
+		ran, err = m.Down(n)
+	case "to":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil || version < 0 {
+			fmt.Fprintln(os.Stderr, "to takes a version number")
+			return 2
+		}
+		ran, err = m.To(version)
+	case "status":
+		return printMigrationStatus(m)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	for _, migration := range ran {
+		fmt.Printf("%04d %s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(ran) == 0 {
+		fmt.Println("Nothing to migrate")
+	}
+	return 0
+}
+
+func printMigrationStatus(m *migrations.Migrator) int {
+	statuses, err := m.Status()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	w.Flush()
+	return 0
+}
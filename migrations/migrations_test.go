@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	mysql, err := MySQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := SQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := Postgres()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mysql) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	sets := map[string][]Migration{"mysql": mysql, "sqlite": sqlite, "postgres": postgres}
+
+	// Every dialect carries the same versions, so a database is at the same
+	// schema version whichever backend it runs on.
+	for dialect, all := range sets {
+		if len(all) != len(mysql) {
+			t.Errorf("%s: got %d migrations, want %d like MySQL", dialect, len(all), len(mysql))
+			continue
+		}
+		for i := range mysql {
+			if all[i].Version != mysql[i].Version || all[i].Name != mysql[i].Name {
+				t.Errorf("%s: migration %d is %04d_%s, want %04d_%s", dialect, i, all[i].Version, all[i].Name, mysql[i].Version, mysql[i].Name)
+			}
+		}
+	}
+
+	for dialect, all := range sets {
+		for i, m := range all {
+			if m.Version != int64(i+1) {
+				t.Errorf("%s: migration %d has version %d, want versions numbered from 1 without gaps", dialect, i, m.Version)
+			}
+			for _, stmt := range append(statements(m.Up), statements(m.Down)...) {
+				if !strings.HasSuffix(stmt, ";") {
+					t.Errorf("%s: version %d: statement does not end in a semicolon: %q", dialect, m.Version, stmt)
+				}
+			}
+		}
+
+		// Every table the repository uses must be created.
+		var up strings.Builder
+		for _, m := range all {
+			up.WriteString(m.Up)
+		}
+		for _, table := range []string{
+			"products", "stock_adjustments", "warehouses", "warehouse_stock",
+			"customers", "customer_addresses", "orders", "order_products",
+			"order_allocations", "order_addresses", "api_keys",
+			"product_prices", "exchange_rates",
+		} {
+			if !strings.Contains(up.String(), "CREATE TABLE "+table+" (") {
+				t.Errorf("%s: no migration creates table %s", dialect, table)
+			}
+		}
+	}
+}
+
+// TestSQLiteUpDown applies the SQLite migrations to a file database, rolls
+// them all back and applies them again.
+func TestSQLiteUpDown(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	all, err := SQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(db, all)
+
+	ran, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(all) {
+		t.Fatalf("Up ran %d migrations, want %d", len(ran), len(all))
+	}
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.To(0); err != nil {
+		t.Fatal(err)
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'schema_%' AND name NOT LIKE 'sqlite_%'").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Fatalf("%d tables left after rolling everything back", tables)
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSQLiteUnitPriceBackfill applies 0006 to a database holding an order
+// line whose product has been deleted, which order lines are allowed to
+// outlive.
+func TestSQLiteUnitPriceBackfill(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	all, err := SQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(db, all)
+	if _, err := m.To(5); err != nil {
+		t.Fatal(err)
+	}
+
+	const now = "2024-05-01 08:30:00"
+	for _, stmt := range []string{
+		"INSERT INTO products (id, name, price, created_at, updated_at) VALUES (1, 'Kopi', 25000, '" + now + "', '" + now + "')",
+		"INSERT INTO products (id, name, price, created_at, updated_at) VALUES (2, 'Teh', 15000, '" + now + "', '" + now + "')",
+		"INSERT INTO orders (id, created_at, updated_at) VALUES (1, '" + now + "', '" + now + "')",
+		"INSERT INTO order_products (order_id, product_id, quantity, created_at, updated_at) VALUES (1, 1, 2, '" + now + "', '" + now + "')",
+		"INSERT INTO order_products (order_id, product_id, quantity, created_at, updated_at) VALUES (1, 2, 1, '" + now + "', '" + now + "')",
+		"DELETE FROM products WHERE id = 2",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := m.To(6); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int64]int64{1: 25000, 2: 0}
+	for productID, price := range want {
+		var got int64
+		if err := db.QueryRow("SELECT unit_price FROM order_products WHERE order_id = 1 AND product_id = ?", productID).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != price {
+			t.Errorf("product %d: got unit price %d, want %d", productID, got, price)
+		}
+	}
+}
+
+func TestLoadRejectsMissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_init.up.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+	}
+	if _, err := Load(fsys, "m"); err == nil {
+		t.Fatal("Load accepted a migration without a down file")
+	}
+}
+
+func TestBind(t *testing.T) {
+	query := "INSERT INTO schema_lock (id, owner, locked_at) VALUES (1, ?, ?)"
+	if got := (&Migrator{}).bind(query); got != query {
+		t.Errorf("MySQL: got %q, want the query unchanged", got)
+	}
+	want := "INSERT INTO schema_lock (id, owner, locked_at) VALUES (1, $1, $2)"
+	if got := (&Migrator{Postgres: true}).bind(query); got != want {
+		t.Errorf("PostgreSQL: got %q, want %q", got, want)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	got := statements("-- comment\nCREATE TABLE a (\n    id INT\n);\n\nDROP TABLE b;\n")
+	want := []string{"CREATE TABLE a (\n    id INT\n);", "DROP TABLE b;"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("statement %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
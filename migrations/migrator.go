@@ -0,0 +1,293 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned when another process holds the migration lock for
+// longer than the lock timeout.
+var ErrLocked = errors.New("migrations: another migration is running (if none is, delete the row in schema_lock)")
+
+// ErrUnknownVersion is returned when asked to migrate to a version that has
+// no migration.
+var ErrUnknownVersion = errors.New("migrations: unknown version")
+
+// Status reports whether one migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// Migrator applies migrations to a database. Applied versions are recorded
+// in schema_migrations, and schema_lock keeps two processes, such as two
+// replicas starting at once, from migrating at the same time.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	// LockTimeout is how long to wait for another process's lock.
+	LockTimeout time.Duration
+	// Postgres writes the migrator's own queries and bookkeeping tables in
+	// PostgreSQL syntax.
+	Postgres bool
+}
+
+// New returns a Migrator applying migrations to db.
+func New(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations, LockTimeout: time.Minute}
+}
+
+// Latest returns the highest version known to the migrator, or 0 if there
+// are no migrations.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the highest applied version. Unlike the other methods it
+// does not create the bookkeeping tables, so it fails on a database that has
+// never been migrated.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	var version int64
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// Check reports an error unless the database is at the latest version.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version != m.Latest() {
+		return fmt.Errorf("migrations: schema is at version %d, want %d", version, m.Latest())
+	}
+	return nil
+}
+
+// Status lists every migration and whether it has been applied.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration. It returns the migrations it ran.
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.To(m.Latest())
+}
+
+// Down rolls back the last n applied migrations. It returns the migrations
+// it rolled back.
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var target int64
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.migrations[i].Version]; !ok {
+			continue
+		}
+		if n == 0 {
+			target = m.migrations[i].Version
+			break
+		}
+		n--
+	}
+	return m.To(target)
+}
+
+// To migrates up or down until version is the latest applied one. Version 0
+// rolls back everything. It returns the migrations it ran, in the order it
+// ran them.
+func (m *Migrator) To(version int64) ([]Migration, error) {
+	if version != 0 && m.find(version) < 0 {
+		return nil, fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+	if err := m.ensureTables(); err != nil {
+		return nil, err
+	}
+
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	defer m.unlock()
+
+	// Read the applied versions only once the lock is held, since another
+	// process may have migrated while this one waited.
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+		if err := m.run(migration, migration.Down); err != nil {
+			return ran, err
+		}
+		if _, err := m.db.Exec(m.bind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+		if err := m.run(migration, migration.Up); err != nil {
+			return ran, err
+		}
+		_, err := m.db.Exec(m.bind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// run executes a migration script. MySQL commits schema changes as it goes,
+// so a script that fails halfway leaves its earlier statements applied and
+// the version unrecorded; the error names the version to fix by hand.
+func (m *Migrator) run(migration Migration, script string) error {
+	for _, stmt := range statements(script) {
+		if _, err := m.db.Exec(stmt); err != nil {
+			return fmt.Errorf("migrations: version %d (%s): %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int64) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) applied() (map[int64]string, error) {
+	if err := m.ensureTables(); err != nil {
+		return nil, err
+	}
+
+	// The cast keeps the SQLite driver from turning the DATETIME column into
+	// a time value, so applied_at reads the same on every database.
+	rows, err := m.db.Query("SELECT version, CAST(applied_at AS CHAR(19)) FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]string)
+	for rows.Next() {
+		var version int64
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) ensureTables() error {
+	datetime := "DATETIME"
+	if m.Postgres {
+		datetime = "TIMESTAMP(0)"
+	}
+
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    applied_at ` + datetime + ` NOT NULL,
+    PRIMARY KEY (version)
+)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_lock (
+    id INT NOT NULL,
+    owner VARCHAR(255) NOT NULL,
+    locked_at ` + datetime + ` NOT NULL,
+    PRIMARY KEY (id)
+)`)
+	return err
+}
+
+// lock takes the single row of schema_lock, retrying until LockTimeout. The
+// primary key makes the insert fail while another process holds the row.
+func (m *Migrator) lock() error {
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		_, err := m.db.Exec(m.bind("INSERT INTO schema_lock (id, owner, locked_at) VALUES (1, ?, ?)"),
+			lockOwner(), time.Now().UTC().Format("2006-01-02 15:04:05"))
+		if err == nil {
+			return nil
+		}
+
+		var held int
+		if err := m.db.QueryRow("SELECT COUNT(*) FROM schema_lock WHERE id = 1").Scan(&held); err != nil {
+			return err
+		}
+		if held == 0 {
+			// The insert failed for some other reason than the lock.
+			return err
+		}
+		if time.Now().After(deadline) {
+			return ErrLocked
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// bind numbers the parameters of query $1, $2, ... for PostgreSQL.
+func (m *Migrator) bind(query string) string {
+	if !m.Postgres {
+		return query
+	}
+	for n := 1; strings.Contains(query, "?"); n++ {
+		query = strings.Replace(query, "?", "$"+strconv.Itoa(n), 1)
+	}
+	return query
+}
+
+func (m *Migrator) unlock() {
+	m.db.Exec("DELETE FROM schema_lock WHERE id = 1")
+}
+
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return host + ":" + strconv.Itoa(os.Getpid())
+}
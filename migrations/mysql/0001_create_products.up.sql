@@ -0,0 +1,13 @@
+CREATE TABLE products (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    price BIGINT NOT NULL,
+    stock BIGINT NOT NULL DEFAULT 0,
+    sold BIGINT NOT NULL DEFAULT 0,
+    reorder_threshold BIGINT NOT NULL DEFAULT 0,
+    stock_policy VARCHAR(16) NOT NULL DEFAULT 'reject',
+    low_stock_alerted TINYINT(1) NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,33 @@
+CREATE TABLE warehouses (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    code VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    latitude DOUBLE NOT NULL DEFAULT 0,
+    longitude DOUBLE NOT NULL DEFAULT 0,
+    priority BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE warehouse_stock (
+    warehouse_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    stock BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (warehouse_id, product_id),
+    KEY warehouse_stock_product_id (product_id),
+    CONSTRAINT warehouse_stock_warehouse_fk FOREIGN KEY (warehouse_id) REFERENCES warehouses (id) ON DELETE CASCADE,
+    CONSTRAINT warehouse_stock_product_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE stock_adjustments (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    product_id BIGINT NOT NULL,
+    warehouse_id BIGINT NULL,
+    delta BIGINT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    KEY stock_adjustments_product_id (product_id),
+    CONSTRAINT stock_adjustments_product_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+DROP TABLE order_addresses;
+DROP TABLE order_allocations;
+DROP TABLE order_products;
+DROP TABLE orders;
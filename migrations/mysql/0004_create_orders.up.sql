@@ -0,0 +1,51 @@
+CREATE TABLE orders (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    customer_id BIGINT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    KEY orders_customer_id (customer_id),
+    CONSTRAINT orders_customer_fk FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Order lines keep referring to products and warehouses that have since been
+-- deleted, so those columns carry no foreign keys.
+CREATE TABLE order_products (
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    backordered_quantity BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (order_id, product_id),
+    KEY order_products_product_id (product_id),
+    CONSTRAINT order_products_order_fk FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE order_allocations (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    warehouse_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    KEY order_allocations_order_product (order_id, product_id),
+    CONSTRAINT order_allocations_order_fk FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE order_addresses (
+    order_id BIGINT NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(64) NOT NULL DEFAULT '',
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(128) NOT NULL,
+    province VARCHAR(128) NOT NULL DEFAULT '',
+    postal_code VARCHAR(32) NOT NULL DEFAULT '',
+    country VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (order_id, type),
+    CONSTRAINT order_addresses_order_fk FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+ALTER TABLE order_products ADD COLUMN unit_price BIGINT NOT NULL DEFAULT 0;
+-- Lines whose product has been deleted keep a unit price of 0.
+UPDATE order_products SET unit_price = COALESCE((SELECT price FROM products WHERE products.id = order_products.product_id), 0);
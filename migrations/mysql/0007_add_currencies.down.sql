@@ -0,0 +1,4 @@
+DROP TABLE exchange_rates;
+DROP TABLE product_prices;
+ALTER TABLE orders DROP COLUMN currency;
+ALTER TABLE products DROP COLUMN currency;
@@ -0,0 +1,17 @@
+ALTER TABLE products ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE orders ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+CREATE TABLE product_prices (
+    product_id BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    price BIGINT NOT NULL,
+    PRIMARY KEY (product_id, currency),
+    CONSTRAINT product_prices_product_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE exchange_rates (
+    currency CHAR(3) NOT NULL,
+    rate DOUBLE NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (currency)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+-- MySQL already stores timestamps as DATETIME. Only PostgreSQL changes in
+-- this version.
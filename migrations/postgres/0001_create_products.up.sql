@@ -0,0 +1,12 @@
+CREATE TABLE products (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    price BIGINT NOT NULL,
+    stock BIGINT NOT NULL DEFAULT 0,
+    sold BIGINT NOT NULL DEFAULT 0,
+    reorder_threshold BIGINT NOT NULL DEFAULT 0,
+    stock_policy VARCHAR(16) NOT NULL DEFAULT 'reject',
+    low_stock_alerted SMALLINT NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP TABLE stock_adjustments;
+DROP TABLE warehouse_stock;
+DROP TABLE warehouses;
@@ -0,0 +1,31 @@
+CREATE TABLE warehouses (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    code VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL DEFAULT 0,
+    longitude DOUBLE PRECISION NOT NULL DEFAULT 0,
+    priority BIGINT NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE warehouse_stock (
+    warehouse_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    stock BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (warehouse_id, product_id),
+    CONSTRAINT warehouse_stock_warehouse_fk FOREIGN KEY (warehouse_id) REFERENCES warehouses (id) ON DELETE CASCADE,
+    CONSTRAINT warehouse_stock_product_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX warehouse_stock_product_id ON warehouse_stock (product_id);
+
+CREATE TABLE stock_adjustments (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    warehouse_id BIGINT NULL,
+    delta BIGINT NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    created_at TEXT NOT NULL,
+    CONSTRAINT stock_adjustments_product_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX stock_adjustments_product_id ON stock_adjustments (product_id);
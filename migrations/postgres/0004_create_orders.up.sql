@@ -0,0 +1,49 @@
+CREATE TABLE orders (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    customer_id BIGINT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    CONSTRAINT orders_customer_fk FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE SET NULL
+);
+CREATE INDEX orders_customer_id ON orders (customer_id);
+
+-- Order lines keep referring to products and warehouses that have since been
+-- deleted, so those columns carry no foreign keys.
+CREATE TABLE order_products (
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    backordered_quantity BIGINT NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (order_id, product_id),
+    CONSTRAINT order_products_order_fk FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
+CREATE INDEX order_products_product_id ON order_products (product_id);
+
+CREATE TABLE order_allocations (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    warehouse_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    created_at TEXT NOT NULL,
+    CONSTRAINT order_allocations_order_fk FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
+CREATE INDEX order_allocations_order_product ON order_allocations (order_id, product_id);
+
+CREATE TABLE order_addresses (
+    order_id BIGINT NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(64) NOT NULL DEFAULT '',
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(128) NOT NULL,
+    province VARCHAR(128) NOT NULL DEFAULT '',
+    postal_code VARCHAR(32) NOT NULL DEFAULT '',
+    country VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (order_id, type),
+    CONSTRAINT order_addresses_order_fk FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
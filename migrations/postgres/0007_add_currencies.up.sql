@@ -0,0 +1,16 @@
+ALTER TABLE products ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE orders ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+CREATE TABLE product_prices (
+    product_id BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    price BIGINT NOT NULL,
+    PRIMARY KEY (product_id, currency),
+    CONSTRAINT product_prices_product_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+
+CREATE TABLE exchange_rates (
+    currency CHAR(3) NOT NULL PRIMARY KEY,
+    rate DOUBLE PRECISION NOT NULL,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1,34 @@
+-- Timestamps were stored as "YYYY-MM-DD HH:MM:SS" text in UTC.
+ALTER TABLE products
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE warehouses
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE stock_adjustments
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE customers
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE orders
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE order_products
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE order_allocations
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE api_keys
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE TIMESTAMPTZ USING last_used_at::timestamp AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ USING revoked_at::timestamp AT TIME ZONE 'UTC';
+
+ALTER TABLE exchange_rates
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at::timestamp AT TIME ZONE 'UTC';
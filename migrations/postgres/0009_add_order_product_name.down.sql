@@ -0,0 +1 @@
+ALTER TABLE order_products DROP COLUMN product_name;
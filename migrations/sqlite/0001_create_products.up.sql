@@ -0,0 +1,14 @@
+-- SQLite has no timestamp type, so timestamps are TEXT in UTC in the
+-- "2006-01-02 15:04:05" layout the repositories write and parse.
+CREATE TABLE products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    price INTEGER NOT NULL,
+    stock INTEGER NOT NULL DEFAULT 0,
+    sold INTEGER NOT NULL DEFAULT 0,
+    reorder_threshold INTEGER NOT NULL DEFAULT 0,
+    stock_policy TEXT NOT NULL DEFAULT 'reject',
+    low_stock_alerted INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1,28 @@
+CREATE TABLE warehouses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT NOT NULL,
+    name TEXT NOT NULL,
+    latitude REAL NOT NULL DEFAULT 0,
+    longitude REAL NOT NULL DEFAULT 0,
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE warehouse_stock (
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses (id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    stock INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (warehouse_id, product_id)
+);
+CREATE INDEX warehouse_stock_product_id ON warehouse_stock (product_id);
+
+CREATE TABLE stock_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    warehouse_id INTEGER NULL,
+    delta INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+CREATE INDEX stock_adjustments_product_id ON stock_adjustments (product_id);
@@ -0,0 +1,24 @@
+CREATE TABLE customers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    -- NOCASE matches MySQL's default collation, under which emails that
+    -- differ only in case are duplicates.
+    email TEXT NOT NULL COLLATE NOCASE,
+    phone TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE UNIQUE INDEX customers_email ON customers (email);
+
+CREATE TABLE customer_addresses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+    label TEXT NOT NULL DEFAULT '',
+    line1 TEXT NOT NULL,
+    line2 TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL,
+    province TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX customer_addresses_customer_id ON customer_addresses (customer_id);
@@ -0,0 +1,45 @@
+CREATE TABLE orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER NULL REFERENCES customers (id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE INDEX orders_customer_id ON orders (customer_id);
+
+-- Order lines keep referring to products and warehouses that have since been
+-- deleted, so those columns carry no foreign keys.
+CREATE TABLE order_products (
+    order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    backordered_quantity INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
+CREATE INDEX order_products_product_id ON order_products (product_id);
+
+CREATE TABLE order_allocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    created_at TEXT NOT NULL
+);
+CREATE INDEX order_allocations_order_product ON order_allocations (order_id, product_id);
+
+CREATE TABLE order_addresses (
+    order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL DEFAULT '',
+    line1 TEXT NOT NULL,
+    line2 TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL,
+    province TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (order_id, type)
+);
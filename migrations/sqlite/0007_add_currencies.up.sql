@@ -0,0 +1,15 @@
+ALTER TABLE products ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+ALTER TABLE orders ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+
+CREATE TABLE product_prices (
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    currency TEXT NOT NULL,
+    price INTEGER NOT NULL,
+    PRIMARY KEY (product_id, currency)
+);
+
+CREATE TABLE exchange_rates (
+    currency TEXT NOT NULL PRIMARY KEY,
+    rate REAL NOT NULL,
+    updated_at TEXT NOT NULL
+);
@@ -0,0 +1,2 @@
+-- SQLite has no timestamp type, so timestamps stay TEXT. Only PostgreSQL
+-- changes in this version.
@@ -0,0 +1,3 @@
+ALTER TABLE order_products ADD COLUMN product_name TEXT NOT NULL DEFAULT '';
+-- Lines whose product has already been deleted keep an empty name.
+UPDATE order_products SET product_name = COALESCE((SELECT name FROM products WHERE products.id = order_products.product_id), '');
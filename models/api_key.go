@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey is a credential for a machine client. Only a hash of the key is
+// stored; Prefix identifies the key in listings.
+type APIKey struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	ID         int64      `json:"id"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Scopes     []string   `json:"scopes"`
+}
+
+// CreatedAPIKey is returned once when a key is created and is the only
+// response that contains the plaintext key
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
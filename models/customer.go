@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Address is a saved address in a customer's address book
+type Address struct {
+	City       string `json:"city"`
+	Country    string `json:"country"`
+	ID         int64  `json:"id"`
+	Label      string `json:"label"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	PostalCode string `json:"postal_code"`
+	Province   string `json:"province"`
+}
+
+type Customer struct {
+	Addresses []Address `json:"addresses"`
+	CreatedAt time.Time `json:"created_at"`
+	Email     string    `json:"email"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
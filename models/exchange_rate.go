@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ExchangeRate is the value of one unit of Currency in the default currency,
+// used to convert amounts for reports
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SalesTotal is the value of the order lines of orders in one currency
+type SalesTotal struct {
+	Currency string `json:"currency"`
+	Orders   int64  `json:"orders"`
+	Total    int64  `json:"total"`
+	// Converted is Total in the report currency.
+	Converted int64 `json:"converted"`
+}
+
+// SalesReport totals the order lines of every order, by order currency and
+// converted into one currency
+type SalesReport struct {
+	Currency   string       `json:"currency"`
+	Total      int64        `json:"total"`
+	Currencies []SalesTotal `json:"currencies"`
+}
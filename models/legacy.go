@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// Version 1 of the API, kept for existing clients, shows each order line as
+// the product's fields with the quantity ordered mixed in. The types below
+// reproduce that shape from the current models.
+
+// LegacyOrderLine is an order line as version 1 of the API shows it
+type LegacyOrderLine struct {
+	Allocations         []Allocation `json:"allocations,omitempty"`
+	BackorderedQuantity int64        `json:"backordered_quantity"`
+	CreatedAt           time.Time    `json:"created_at"`
+	ID                  int64        `json:"id"`
+	Name                string       `json:"name"`
+	Price               int64        `json:"price"`
+	Quantity            int64        `json:"quantity"`
+	Sold                int64        `json:"sold"`
+	Stock               int64        `json:"stock"`
+	UpdatedAt           time.Time    `json:"updated_at"`
+}
+
+// LegacyOrder is an order as version 1 of the API shows it
+type LegacyOrder struct {
+	BillingAddress  *OrderAddress     `json:"billing_address,omitempty"`
+	CreatedAt       *time.Time        `json:"created_at,omitempty"`
+	Currency        string            `json:"currency,omitempty"`
+	CustomerID      *int64            `json:"customer_id,omitempty"`
+	ID              *int64            `json:"id,omitempty"`
+	Products        []LegacyOrderLine `json:"products,omitempty"`
+	ShippingAddress *OrderAddress     `json:"shipping_address,omitempty"`
+	Status          string            `json:"status,omitempty"`
+	UpdatedAt       *time.Time        `json:"updated_at,omitempty"`
+}
+
+type LegacyDetailOrder struct {
+	Data    LegacyOrder `json:"data"`
+	Message string      `json:"message"`
+}
+
+type LegacyListOrder struct {
+	Data    []LegacyOrder `json:"data"`
+	Message string        `json:"message"`
+}
+
+// Legacy returns the order in the shape version 1 of the API uses. Prices
+// are the products' current prices, as version 1 has always shown them.
+func (o Order) Legacy() LegacyOrder {
+	legacy := LegacyOrder{
+		BillingAddress:  o.BillingAddress,
+		CreatedAt:       o.CreatedAt,
+		Currency:        o.Currency,
+		CustomerID:      o.CustomerID,
+		ID:              o.ID,
+		ShippingAddress: o.ShippingAddress,
+		Status:          o.Status,
+		UpdatedAt:       o.UpdatedAt,
+	}
+	for _, line := range o.Lines {
+		legacy.Products = append(legacy.Products, LegacyOrderLine{
+			Allocations:         line.Allocations,
+			BackorderedQuantity: line.BackorderedQuantity,
+			CreatedAt:           line.Product.CreatedAt,
+			ID:                  line.Product.ID,
+			Name:                line.Product.Name,
+			Price:               line.Product.Price,
+			Quantity:            line.Quantity,
+			Sold:                line.Product.Sold,
+			Stock:               line.Product.Stock,
+			UpdatedAt:           line.Product.UpdatedAt,
+		})
+	}
+	return legacy
+}
+
+// LegacyOrders returns orders in the shape version 1 of the API uses.
+func LegacyOrders(orders []Order) []LegacyOrder {
+	if orders == nil {
+		return nil
+	}
+	legacy := make([]LegacyOrder, len(orders))
+	for i, order := range orders {
+		legacy[i] = order.Legacy()
+	}
+	return legacy
+}
@@ -1,52 +1,56 @@
 package models
 
-type OrderDetailResponse struct {
-	Message string      `json:"message"`
-	Data    OrderDetail `json:"data"`
-}
-
-type CreateOrderResponse struct {
-	Message string `json:"message"`
-	Data    Order  `json:"data"`
-}
-
-// OrderDetail memuat detail informasi order dan produk yang terkait
-type OrderDetail struct {
-	ID        *int64    `json:"id"`
-	Products  []Product `json:"products"`
-	CreatedAt *string   `json:"created_at"`
-	UpdatedAt *string   `json:"updated_at"`
-}
+import "time"
 
-type DetailOrder struct {
-	Data    Order  `json:"data"`
-	Message string `json:"message"`
-}
+// OrderStatusPending is the status of an order that has not been fulfilled
+// yet; only pending orders can be changed.
+const OrderStatusPending = "pending"
 
-type ListOrder struct {
-	Data    []Order `json:"data"`
-	Message string  `json:"message"`
+// OrderAddress adalah salinan alamat yang disimpan bersama order, sehingga
+// perubahan pada buku alamat pelanggan tidak mengubah order yang sudah ada
+type OrderAddress struct {
+	City       string `json:"city"`
+	Country    string `json:"country"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	Name       string `json:"name"`
+	Phone      string `json:"phone"`
+	PostalCode string `json:"postal_code"`
+	Province   string `json:"province"`
 }
 
 type Order struct {
-	CreatedAt *string   `json:"created_at,omitempty"`
-	ID        *int64    `json:"id,omitempty"`
-	Products  []Product `json:"products,omitempty"`
-	UpdatedAt *string   `json:"updated_at,omitempty"`
-}
-
-type Product struct {
-	CreatedAt string `json:"created_at"`
-	ID        int64  `json:"id"`
-	Name      string `json:"name"`
-	Price     int64  `json:"price"`
-	Quantity  int64  `json:"quantity"`
-	Sold      int64  `json:"sold"`
-	Stock     int64  `json:"stock"`
-	UpdatedAt string `json:"updated_at"`
-}
-
-type DeleteOrder struct {
-	Data    Order  `json:"data"`
-	Message string `json:"message"`
+	BillingAddress  *OrderAddress `json:"billing_address,omitempty"`
+	CreatedAt       *time.Time    `json:"created_at,omitempty"`
+	Currency        string        `json:"currency,omitempty"`
+	CustomerID      *int64        `json:"customer_id,omitempty"`
+	ID              *int64        `json:"id,omitempty"`
+	Lines           []OrderLine   `json:"lines,omitempty"`
+	ShippingAddress *OrderAddress `json:"shipping_address,omitempty"`
+	Status          string        `json:"status,omitempty"`
+	UpdatedAt       *time.Time    `json:"updated_at,omitempty"`
+}
+
+// OrderLine is a product on an order. UnitPrice is the product's price in
+// the order currency when the line was added, so later price changes do not
+// alter the order.
+type OrderLine struct {
+	Allocations         []Allocation `json:"allocations,omitempty"`
+	BackorderedQuantity int64        `json:"backordered_quantity"`
+	LineTotal           int64        `json:"line_total"`
+	Product             Product      `json:"product"`
+	Quantity            int64        `json:"quantity"`
+	UnitPrice           int64        `json:"unit_price"`
+}
+
+// NewOrderLine returns a line of quantity units of product at unitPrice.
+func NewOrderLine(product Product, quantity, unitPrice, backordered int64, allocations []Allocation) OrderLine {
+	return OrderLine{
+		Allocations:         allocations,
+		BackorderedQuantity: backordered,
+		LineTotal:           quantity * unitPrice,
+		Product:             product,
+		Quantity:            quantity,
+		UnitPrice:           unitPrice,
+	}
 }
@@ -1,36 +1,82 @@
 package models
 
-type ApidogModel struct {
-	Data    []Datum `json:"data"`
-	Message string  `json:"message"`
+import "time"
+
+// Stock policies decide what happens when an order asks for more than is in stock.
+const (
+	// StockPolicyReject refuses the order.
+	StockPolicyReject = "reject"
+	// StockPolicyBackorder ships what is in stock and backorders the shortfall.
+	StockPolicyBackorder = "backorder"
+	// StockPolicyPreorder backorders the whole quantity until new stock arrives.
+	StockPolicyPreorder = "preorder"
+)
+
+// ValidStockPolicy reports whether policy is one of the known stock policies.
+func ValidStockPolicy(policy string) bool {
+	switch policy {
+	case StockPolicyReject, StockPolicyBackorder, StockPolicyPreorder:
+		return true
+	}
+	return false
+}
+
+// Product is a product with its stock, as every endpoint returns it. Price
+// is in Currency; Prices lists fixed prices in other currencies. Prices are
+// whole units of their currency, not minor units: a USD price of 40 is forty
+// dollars, and amounts smaller than one unit cannot be expressed.
+type Product struct {
+	CreatedAt        time.Time        `json:"created_at"`
+	Currency         string           `json:"currency"`
+	ID               int64            `json:"id"`
+	Name             string           `json:"name"`
+	Price            int64            `json:"price"`
+	Prices           []ProductPrice   `json:"prices,omitempty"`
+	ReorderThreshold int64            `json:"reorder_threshold"`
+	Sold             int64            `json:"sold"`
+	Stock            int64            `json:"stock"`
+	StockPolicy      string           `json:"stock_policy"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+	Warehouses       []WarehouseStock `json:"warehouses,omitempty"`
 }
 
-type Datum struct {
-	CreatedAt string `json:"created_at"`
-	ID        int64  `json:"id"`
-	Name      string `json:"name"`
-	Price     int64  `json:"price"`
-	Sold      int64  `json:"sold"`
-	Stock     int64  `json:"stock"`
-	UpdatedAt string `json:"updated_at"`
+// ProductPrice is the price of a product in a currency other than its own
+type ProductPrice struct {
+	Currency string `json:"currency"`
+	Price    int64  `json:"price"`
 }
 
-type CreateProduct struct {
-	Data    Data   `json:"data"`
-	Message string `json:"message"`
+// PriceIn returns the price of the product in currency, and whether it has
+// one.
+func (p Product) PriceIn(currency string) (int64, bool) {
+	if currency == p.Currency {
+		return p.Price, true
+	}
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price.Price, true
+		}
+	}
+	return 0, false
 }
 
-type DetailProduct struct {
-	Data    Data   `json:"data"`
-	Message string `json:"message"`
+// StockAdjustment mencatat perubahan stok relatif beserta alasannya
+type StockAdjustment struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	Delta     int64     `json:"delta"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// WarehouseID is the warehouse whose stock changed, or zero for stock
+	// not yet assigned to a warehouse.
+	WarehouseID int64 `json:"warehouse_id,omitempty"`
+
+	// BackordersAllocated is how many backordered units the adjustment filled.
+	BackordersAllocated int64 `json:"backorders_allocated"`
 }
 
-type Data struct {
-	CreatedAt string `json:"created_at"`
-	ID        int64  `json:"id"`
-	Name      string `json:"name"`
-	Price     int64  `json:"price"`
-	Sold      int64  `json:"sold"`
-	Stock     int64  `json:"stock"`
-	UpdatedAt string `json:"updated_at"`
+type StockAdjustmentResult struct {
+	Adjustment StockAdjustment `json:"adjustment"`
+	Product    Product         `json:"product"`
 }
@@ -0,0 +1,9 @@
+package models
+
+// Response is the envelope of every successful response. APIVersion is set
+// from version 2 of the API on; version 1 responses leave it out.
+type Response struct {
+	APIVersion int         `json:"api_version,omitempty"`
+	Data       interface{} `json:"data"`
+	Message    string      `json:"message"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Allocation rules decide which warehouses fulfil an order line.
+const (
+	// AllocationNearest ships from the warehouse closest to the destination.
+	AllocationNearest = "nearest"
+	// AllocationPreferred ships from the requested warehouse, or the one with
+	// the best priority when none is requested.
+	AllocationPreferred = "preferred"
+	// AllocationSplit spreads a line across warehouses in priority order.
+	AllocationSplit = "split"
+)
+
+// ValidAllocationRule reports whether rule is one of the known allocation rules.
+func ValidAllocationRule(rule string) bool {
+	switch rule {
+	case AllocationNearest, AllocationPreferred, AllocationSplit:
+		return true
+	}
+	return false
+}
+
+type Warehouse struct {
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Name      string    `json:"name"`
+	Priority  int64     `json:"priority"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WarehouseStock is a product's availability at a single warehouse
+type WarehouseStock struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Stock       int64  `json:"stock"`
+	WarehouseID int64  `json:"warehouse_id"`
+}
+
+// Allocation is the quantity of an order line shipped from a warehouse
+type Allocation struct {
+	Quantity    int64 `json:"quantity"`
+	WarehouseID int64 `json:"warehouse_id"`
+}
+
+// AllocationRequest selects the allocation rule for a new order. Latitude and
+// Longitude locate the destination for the nearest rule, and WarehouseID names
+// the warehouse for the preferred rule.
+type AllocationRequest struct {
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Rule        string   `json:"rule"`
+	WarehouseID int64    `json:"warehouse_id"`
+}
@@ -0,0 +1,10 @@
+#!/bin/sh
+# Downloads the Swagger UI files served under /docs into swagger-ui/.
+# Run through go generate ./openapi; the version must match
+# swaggerUIVersion in openapi.go.
+set -eu
+
+version=5.17.14
+for file in swagger-ui.css swagger-ui-bundle.js; do
+	curl -fsSL -o "swagger-ui/$file" "https://unpkg.com/swagger-ui-dist@$version/$file"
+done
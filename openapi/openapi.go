@@ -0,0 +1,74 @@
+// Package openapi serves the OpenAPI document describing the API and a
+// Swagger UI page for browsing it.
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+//go:generate sh fetch-swagger-ui.sh
+
+// swaggerUIVersion is the swagger-ui-dist release the page uses, fetched
+// into swagger-ui/ by go generate.
+const swaggerUIVersion = "5.17.14"
+
+// Spec is the OpenAPI 3.1 document for every route the router registers.
+// Each operationId is the name of the route it describes.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed swagger.html
+var pageTemplate string
+
+//go:embed swagger-ui
+var assetFiles embed.FS
+
+var (
+	assets = mustSub(assetFiles, "swagger-ui")
+	page   = renderPage()
+)
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// renderPage fills in where the page loads Swagger UI from: the files
+// embedded in the binary, or the CDN when they have not been fetched.
+func renderPage() []byte {
+	base := "/docs/"
+	if _, err := fs.Stat(assets, "swagger-ui-bundle.js"); err != nil {
+		base = "https://unpkg.com/swagger-ui-dist@" + swaggerUIVersion + "/"
+	}
+
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("swagger.html").Parse(pageTemplate))
+	if err := tmpl.Execute(&buf, struct{ Assets string }{base}); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// SpecHandler serves the OpenAPI document.
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(Spec)
+}
+
+// UIHandler serves a Swagger UI page that loads the document from
+// /openapi.json.
+func UIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// AssetHandler serves the Swagger UI scripts and styles under /docs/.
+var AssetHandler = http.StripPrefix("/docs/", http.FileServer(http.FS(assets)))
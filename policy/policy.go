@@ -0,0 +1,143 @@
+// Package policy decides which roles may perform which operations.
+package policy
+
+import "api-productnorder/auth"
+
+// Roles recognised in the roles claim of a token.
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+// Action is an operation guarded by the policy.
+type Action string
+
+const (
+	ReadProducts     Action = "products:read"
+	WriteProducts    Action = "products:write"
+	DeleteProducts   Action = "products:delete"
+	ReadInventory    Action = "inventory:read"
+	ReadWarehouses   Action = "warehouses:read"
+	WriteWarehouses  Action = "warehouses:write"
+	DeleteWarehouses Action = "warehouses:delete"
+	ReadCustomers    Action = "customers:read"
+	WriteCustomers   Action = "customers:write"
+	DeleteCustomers  Action = "customers:delete"
+	ReadOrders       Action = "orders:read"
+	CreateOrders     Action = "orders:create"
+	WriteOrders      Action = "orders:write"
+	DeleteOrders     Action = "orders:delete"
+	ManageAPIKeys    Action = "api-keys:manage"
+	ReadReports      Action = "reports:read"
+	ManageRates      Action = "exchange-rates:manage"
+)
+
+// Decision is the outcome of a policy check.
+type Decision int
+
+const (
+	// Deny refuses the operation.
+	Deny Decision = iota
+	// AllowOwn permits the operation only on the caller's own customer
+	// record and orders.
+	AllowOwn
+	// Allow permits the operation on any record.
+	Allow
+)
+
+// Allowed reports whether the decision permits the operation in some form.
+func (d Decision) Allowed() bool {
+	return d != Deny
+}
+
+// rules lists, for each action, the decision granted to each role. Anyone
+// may read the catalogue; customers may create and view their own orders and
+// customer record; staff manage the catalogue, warehouses, customers and all
+// orders and read reports; only admins may delete or set exchange rates.
+var rules = map[Action]map[string]Decision{
+	ReadProducts:     {"": Allow, RoleCustomer: Allow, RoleStaff: Allow, RoleAdmin: Allow},
+	WriteProducts:    {RoleStaff: Allow, RoleAdmin: Allow},
+	DeleteProducts:   {RoleAdmin: Allow},
+	ReadInventory:    {RoleStaff: Allow, RoleAdmin: Allow},
+	ReadWarehouses:   {RoleStaff: Allow, RoleAdmin: Allow},
+	WriteWarehouses:  {RoleStaff: Allow, RoleAdmin: Allow},
+	DeleteWarehouses: {RoleAdmin: Allow},
+	ReadCustomers:    {RoleCustomer: AllowOwn, RoleStaff: Allow, RoleAdmin: Allow},
+	WriteCustomers:   {RoleStaff: Allow, RoleAdmin: Allow},
+	DeleteCustomers:  {RoleAdmin: Allow},
+	ReadOrders:       {RoleCustomer: AllowOwn, RoleStaff: Allow, RoleAdmin: Allow},
+	CreateOrders:     {RoleCustomer: AllowOwn, RoleStaff: Allow, RoleAdmin: Allow},
+	WriteOrders:      {RoleStaff: Allow, RoleAdmin: Allow},
+	DeleteOrders:     {RoleAdmin: Allow},
+	ManageAPIKeys:    {RoleAdmin: Allow},
+	ReadReports:      {RoleStaff: Allow, RoleAdmin: Allow},
+	ManageRates:      {RoleAdmin: Allow},
+}
+
+// API key scopes accepted for machine clients.
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeOrdersRead    = "orders:read"
+	ScopeOrdersWrite   = "orders:write"
+)
+
+// Scopes lists every scope an API key may be granted.
+var Scopes = []string{ScopeProductsRead, ScopeProductsWrite, ScopeOrdersRead, ScopeOrdersWrite}
+
+// ValidScope reports whether scope is one of Scopes.
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeRules maps actions to the API key scope that permits them. Actions
+// not listed here are never available to API keys.
+var scopeRules = map[Action]string{
+	ReadProducts:  ScopeProductsRead,
+	ReadInventory: ScopeProductsRead,
+	WriteProducts: ScopeProductsWrite,
+	ReadOrders:    ScopeOrdersRead,
+	CreateOrders:  ScopeOrdersWrite,
+	WriteOrders:   ScopeOrdersWrite,
+}
+
+// Decide returns the most permissive decision any of the principal's roles
+// grants for action. Anonymous callers are judged by the "" entry and API
+// keys by their scopes. A nil principal means authentication is disabled, and
+// everything is allowed.
+func Decide(p *auth.Principal, action Action) Decision {
+	if p == nil {
+		return Allow
+	}
+
+	if p.IsAPIKey() {
+		if scope, ok := scopeRules[action]; ok && p.HasScope(scope) {
+			return Allow
+		}
+		return Deny
+	}
+
+	granted := rules[action]
+	if p.IsAnonymous() {
+		return granted[""]
+	}
+
+	decision := Deny
+	for _, role := range p.Roles {
+		if d := granted[role]; d > decision {
+			decision = d
+		}
+	}
+
+	// Own-record access needs to know which customer the caller is.
+	if decision == AllowOwn && p.CustomerID == 0 {
+		return Deny
+	}
+	return decision
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreKeys(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 1, Period: time.Minute}
+	now := time.Now()
+
+	if result, _ := s.Take("a", limit, now); !result.Allowed {
+		t.Fatal("a: first request refused")
+	}
+	if result, _ := s.Take("a", limit, now); result.Allowed {
+		t.Error("a: second request allowed")
+	}
+	if result, _ := s.Take("b", limit, now); !result.Allowed {
+		t.Error("b: refused because a is spent")
+	}
+}
+
+// TestMemoryStoreSweep checks that idle buckets are dropped once they have
+// refilled, and that buckets still in use are kept.
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Requests: 1, Period: time.Minute}
+	start := time.Now()
+
+	s.Take("idle", limit, start)
+	s.Take("recent", limit, start.Add(30*time.Second))
+	// Call sweepEvery sweeps a minute after the idle bucket was last used.
+	for i := 2; i < sweepEvery; i++ {
+		s.Take("busy", limit, start.Add(time.Minute))
+	}
+
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+	for _, key := range []string{"recent", "busy"} {
+		if _, ok := s.buckets[key]; !ok {
+			t.Errorf("bucket %q was swept before refilling", key)
+		}
+	}
+
+	// A swept key starts again with a full bucket.
+	if result, _ := s.Take("idle", limit, start.Add(time.Minute)); !result.Allowed {
+		t.Error("swept key refused")
+	}
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"api-productnorder/auth"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Config sets the limit applied to each route, by route name. Routes not
+// listed use Default; a zero Default leaves them unlimited. IP limits each
+// remote address across all routes before authentication, so that requests
+// with bad credentials are throttled too; routes with a zero limit are exempt
+// from it, and a zero IP leaves addresses unlimited.
+type Config struct {
+	Default Limit
+	Routes  map[string]Limit
+	IP      Limit
+}
+
+func (c Config) limitFor(route string) Limit {
+	if limit, ok := c.Routes[route]; ok {
+		return limit
+	}
+	return c.Default
+}
+
+// Middleware limits each client separately on each route. Clients are
+// identified by API key, then by token subject, and otherwise by remote IP,
+// so it must run after the auth middleware. Responses carry RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, and
+// rejected requests get 429 with Retry-After. If the store fails the request
+// is let through.
+func Middleware(store Store, cfg Config) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeName(r)
+			serve(w, r, next, store, auth.ClientKey(r)+"|"+route, cfg.limitFor(route))
+		})
+	}
+}
+
+// IPMiddleware limits each remote address to cfg.IP across all routes. It
+// must run before the auth middleware, which looks up every API key and may
+// be probed with any number of bad credentials. Responses carry the same
+// headers as Middleware's.
+func IPMiddleware(store Store, cfg Config) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit := cfg.limitFor(routeName(r)); limit.Requests <= 0 || limit.Period <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// No principal is known yet, so ClientKey names the remote IP.
+			serve(w, r, next, store, auth.ClientKey(r)+"|*", cfg.IP)
+		})
+	}
+}
+
+func routeName(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		return current.GetName()
+	}
+	return ""
+}
+
+// serve takes a token for key and passes the request on to next, or rejects
+// it with 429 when the bucket is empty.
+func serve(w http.ResponseWriter, r *http.Request, next http.Handler, store Store, key string, limit Limit) {
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	result, err := store.Take(key, limit, time.Now())
+	if err != nil {
+		log.Println("Rate limit store failed:", err) // Log error
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+	h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(seconds(limit.Period)))
+
+	if !result.Allowed {
+		h.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// seconds rounds d up to whole seconds, as the rate limit headers require.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"api-productnorder/auth"
+
+	"github.com/gorilla/mux"
+)
+
+var testConfig = Config{
+	Default: Limit{Requests: 2, Period: time.Hour},
+	Routes: map[string]Limit{
+		"strict": {Requests: 1, Period: time.Hour},
+		"free":   {},
+	},
+	IP: Limit{Requests: 3, Period: time.Hour},
+}
+
+// newRouter serves the routes "default", "strict" and "free", each answering
+// 200, behind the given middleware.
+func newRouter(middleware ...mux.MiddlewareFunc) *mux.Router {
+	r := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	for _, name := range []string{"default", "strict", "free"} {
+		r.HandleFunc("/"+name, ok).Name(name)
+	}
+	r.Use(middleware...)
+	return r
+}
+
+func get(r http.Handler, path, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = remoteAddr
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	r := newRouter(Middleware(NewMemoryStore(), testConfig))
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		status     int
+		// headers are the rate limit headers expected; nil means none.
+		headers map[string]string
+	}{
+		{"first", "/default", "192.0.2.1:1000", http.StatusOK, map[string]string{
+			"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "RateLimit-Reset": "1800", "RateLimit-Policy": "2;w=3600", "Retry-After": "",
+		}},
+		{"last", "/default", "192.0.2.1:1001", http.StatusOK, map[string]string{
+			"RateLimit-Remaining": "0", "RateLimit-Reset": "3600", "Retry-After": "",
+		}},
+		{"over the limit", "/default", "192.0.2.1:1002", http.StatusTooManyRequests, map[string]string{
+			"RateLimit-Limit": "2", "RateLimit-Remaining": "0", "Retry-After": "1800",
+		}},
+		{"other client", "/default", "192.0.2.2:1000", http.StatusOK, map[string]string{
+			"RateLimit-Remaining": "1",
+		}},
+		{"route override", "/strict", "192.0.2.1:1000", http.StatusOK, map[string]string{
+			"RateLimit-Limit": "1", "RateLimit-Remaining": "0", "RateLimit-Policy": "1;w=3600",
+		}},
+		{"route override spent", "/strict", "192.0.2.1:1000", http.StatusTooManyRequests, map[string]string{
+			"Retry-After": "3600",
+		}},
+		{"unlimited route", "/free", "192.0.2.1:1000", http.StatusOK, nil},
+		{"unlimited route again", "/free", "192.0.2.1:1000", http.StatusOK, nil},
+	}
+
+	for _, tt := range tests {
+		rec := get(r, tt.path, tt.remoteAddr, nil)
+		if rec.Code != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.headers == nil && rec.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("%s: unexpected rate limit headers %v", tt.name, rec.Header())
+		}
+		for key, want := range tt.headers {
+			if got := rec.Header().Get(key); got != want {
+				t.Errorf("%s: got %s %q, want %q", tt.name, key, got, want)
+			}
+		}
+	}
+}
+
+// TestMiddlewareByPrincipal checks that authenticated clients are limited by
+// identity, so two API keys behind one address have separate buckets.
+func TestMiddlewareByPrincipal(t *testing.T) {
+	limiter := Middleware(NewMemoryStore(), testConfig)
+	r := newRouter(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := &auth.Principal{Subject: "api-key", APIKeyID: 1}
+			if r.Header.Get("X-Key") == "2" {
+				p = &auth.Principal{Subject: "api-key", APIKeyID: 2}
+			}
+			limiter(next).ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+		})
+	})
+
+	for _, key := range []string{"1", "1", "2", "2"} {
+		if rec := get(r, "/default", "192.0.2.1:1000", http.Header{"X-Key": {key}}); rec.Code != http.StatusOK {
+			t.Errorf("key %s: got %d, want 200", key, rec.Code)
+		}
+	}
+	if rec := get(r, "/default", "192.0.2.1:1000", http.Header{"X-Key": {"1"}}); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("key 1 over the limit: got %d, want 429", rec.Code)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("store down")
+}
+
+func TestMiddlewareStoreFailure(t *testing.T) {
+	r := newRouter(Middleware(failingStore{}, testConfig), IPMiddleware(failingStore{}, testConfig))
+
+	for i := 0; i < 3; i++ {
+		rec := get(r, "/strict", "192.0.2.1:1000", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want 200", i, rec.Code)
+		}
+		if rec.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("request %d: unexpected rate limit headers %v", i, rec.Header())
+		}
+	}
+}
+
+// countingKeyStore rejects every API key and counts the lookups.
+type countingKeyStore struct {
+	mu      sync.Mutex
+	lookups int
+}
+
+func (s *countingKeyStore) LookupAPIKey(string) (*auth.Principal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lookups++
+	return nil, auth.ErrInvalidAPIKey
+}
+
+// TestIPMiddleware checks that the limit per address applies ahead of
+// authentication, so bad API keys stop reaching the key store.
+func TestIPMiddleware(t *testing.T) {
+	keys := &countingKeyStore{}
+	r := newRouter(IPMiddleware(NewMemoryStore(), testConfig), auth.Middleware(nil, keys))
+	badKey := http.Header{auth.APIKeyHeader: {"pno_bad"}}
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i, status := range want {
+		rec := get(r, "/default", "192.0.2.1:1000", badKey)
+		if rec.Code != status {
+			t.Errorf("request %d: got %d, want %d", i, rec.Code, status)
+		}
+	}
+	if rec := get(r, "/strict", "192.0.2.1:1000", badKey); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("limit per address is not shared across routes: got %d, want 429", rec.Code)
+	}
+	if keys.lookups != 3 {
+		t.Errorf("got %d key lookups, want 3", keys.lookups)
+	}
+
+	if rec := get(r, "/default", "192.0.2.2:1000", badKey); rec.Code != http.StatusUnauthorized {
+		t.Errorf("other address: got %d, want 401", rec.Code)
+	}
+	if rec := get(r, "/free", "192.0.2.1:1000", badKey); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unlimited route: got %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "10/1m", want: Limit{Requests: 10, Period: time.Minute}},
+		{in: " 100 / 1s ", want: Limit{Requests: 100, Period: time.Second}},
+		{in: "10", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "x/1m", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q): got error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q): got %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestBucketRefill drains a bucket of ten tokens refilled one per second and
+// checks every result as it refills.
+func TestBucketRefill(t *testing.T) {
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var b bucket
+
+	if got, want := b.take(limit, start), (Result{Allowed: true, Remaining: 9, Reset: time.Second}); got != want {
+		t.Fatalf("first take: got %+v, want %+v", got, want)
+	}
+	for i := 0; i < 9; i++ {
+		b.take(limit, start)
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  Result
+	}{
+		{"empty", 0, Result{Reset: 10 * time.Second, RetryAfter: time.Second}},
+		{"half a token", 500 * time.Millisecond, Result{Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{"one and a half tokens", 1500 * time.Millisecond, Result{Allowed: true, Reset: 9500 * time.Millisecond}},
+		{"clock going backwards", time.Second, Result{RetryAfter: 500 * time.Millisecond, Reset: 9500 * time.Millisecond}},
+		{"refilled past capacity", time.Hour, Result{Allowed: true, Remaining: 9, Reset: time.Second}},
+	}
+
+	for _, tt := range tests {
+		if got := b.take(limit, start.Add(tt.after)); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+)
+
+// warehouseCandidate is a warehouse holding stock of the product being allocated.
+type warehouseCandidate struct {
+	WarehouseID int64
+	Stock       int64
+	Latitude    float64
+	Longitude   float64
+	Priority    int64
+}
+
+// planAllocation decides which warehouses ship quantity units of a product.
+// The nearest and preferred rules ship from a single warehouse when one can
+// cover the whole quantity and otherwise split in the same order of
+// preference; the split rule always fills warehouses in priority order. The
+// returned allocations may total less than quantity, in which case the
+// remainder comes from stock not assigned to any warehouse.
+func planAllocation(req models.AllocationRequest, candidates []warehouseCandidate, quantity int64) []models.Allocation {
+	ordered := make([]warehouseCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Stock > 0 {
+			ordered = append(ordered, c)
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		switch {
+		case req.Rule == models.AllocationNearest && req.Latitude != nil && req.Longitude != nil:
+			da := distanceKm(*req.Latitude, *req.Longitude, a.Latitude, a.Longitude)
+			db := distanceKm(*req.Latitude, *req.Longitude, b.Latitude, b.Longitude)
+			if da != db {
+				return da < db
+			}
+		case req.Rule == models.AllocationPreferred && req.WarehouseID != 0:
+			if (a.WarehouseID == req.WarehouseID) != (b.WarehouseID == req.WarehouseID) {
+				return a.WarehouseID == req.WarehouseID
+			}
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.WarehouseID < b.WarehouseID
+	})
+
+	if req.Rule != models.AllocationSplit {
+		for _, c := range ordered {
+			if c.Stock >= quantity {
+				return []models.Allocation{{WarehouseID: c.WarehouseID, Quantity: quantity}}
+			}
+		}
+	}
+
+	var allocations []models.Allocation
+	remaining := quantity
+	for _, c := range ordered {
+		if remaining == 0 {
+			break
+		}
+		n := c.Stock
+		if n > remaining {
+			n = remaining
+		}
+		allocations = append(allocations, models.Allocation{WarehouseID: c.WarehouseID, Quantity: n})
+		remaining -= n
+	}
+
+	return allocations
+}
+
+// distanceKm returns the great-circle distance between two coordinates.
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// allocateWarehouseStock plans and records the warehouses that ship quantity
+// units of a product for an order, decrementing their stock. Warehouse rows
+// are locked for the rest of the caller's transaction.
+func allocateWarehouseStock(tx *transaction, orderID, productID, quantity int64, req models.AllocationRequest, now time.Time) ([]models.Allocation, error) {
+	rows, err := tx.Query(`
+		SELECT w.id, ws.stock, w.latitude, w.longitude, w.priority
+		FROM warehouse_stock ws
+		JOIN warehouses w ON ws.warehouse_id = w.id
+		WHERE ws.product_id = ? AND ws.stock > 0
+		FOR UPDATE`, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []warehouseCandidate
+	for rows.Next() {
+		var c warehouseCandidate
+		if err := rows.Scan(&c.WarehouseID, &c.Stock, &c.Latitude, &c.Longitude, &c.Priority); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	allocations := planAllocation(req, candidates, quantity)
+	for _, allocation := range allocations {
+		if err := recordAllocation(tx, orderID, productID, allocation, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return allocations, nil
+}
+
+// recordAllocation takes allocated units out of a warehouse and links them to an order line.
+func recordAllocation(tx *transaction, orderID, productID int64, allocation models.Allocation, now time.Time) error {
+	_, err := tx.Exec("UPDATE warehouse_stock SET stock = stock - ? WHERE warehouse_id = ? AND product_id = ?",
+		allocation.Quantity, allocation.WarehouseID, productID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO order_allocations (order_id, product_id, warehouse_id, quantity, created_at) VALUES (?, ?, ?, ?, ?)",
+		orderID, productID, allocation.WarehouseID, allocation.Quantity, now)
+	return err
+}
+
+// queryer is implemented by both *sql.DB and *transaction.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getOrderAllocations returns warehouse allocations keyed by order and product ID.
+func getOrderAllocations(q queryer, where string, args ...interface{}) (map[[2]int64][]models.Allocation, error) {
+	rows, err := q.Query(`
+		SELECT order_id, product_id, warehouse_id, SUM(quantity)
+		FROM order_allocations
+		`+where+`
+		GROUP BY order_id, product_id, warehouse_id
+		ORDER BY order_id, product_id, warehouse_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocations := make(map[[2]int64][]models.Allocation)
+	for rows.Next() {
+		var orderID, productID int64
+		var allocation models.Allocation
+		if err := rows.Scan(&orderID, &productID, &allocation.WarehouseID, &allocation.Quantity); err != nil {
+			return nil, err
+		}
+		key := [2]int64{orderID, productID}
+		allocations[key] = append(allocations[key], allocation)
+	}
+
+	return allocations, rows.Err()
+}
+
+// releaseWarehouseAllocations returns up to quantity units of an order line to
+// the warehouses they were allocated from, most recent allocation first. Units
+// that were never allocated to a warehouse came from unassigned stock and need
+// no warehouse change.
+func releaseWarehouseAllocations(tx *transaction, orderID, productID, quantity int64) error {
+	if quantity <= 0 {
+		return nil
+	}
+
+	rows, err := tx.Query("SELECT id, warehouse_id, quantity FROM order_allocations WHERE order_id = ? AND product_id = ? ORDER BY id DESC", orderID, productID)
+	if err != nil {
+		return err
+	}
+
+	type allocationRow struct {
+		id          int64
+		warehouseID int64
+		quantity    int64
+	}
+	var allocations []allocationRow
+	for rows.Next() {
+		var a allocationRow
+		if err := rows.Scan(&a.id, &a.warehouseID, &a.quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		allocations = append(allocations, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, a := range allocations {
+		if quantity == 0 {
+			break
+		}
+
+		n := a.quantity
+		if n > quantity {
+			n = quantity
+		}
+
+		_, err := tx.Exec("UPDATE warehouse_stock SET stock = stock + ? WHERE warehouse_id = ? AND product_id = ?", n, a.warehouseID, productID)
+		if err != nil {
+			return err
+		}
+
+		if n == a.quantity {
+			_, err = tx.Exec("DELETE FROM order_allocations WHERE id = ?", a.id)
+		} else {
+			_, err = tx.Exec("UPDATE order_allocations SET quantity = quantity - ? WHERE id = ?", n, a.id)
+		}
+		if err != nil {
+			return err
+		}
+
+		quantity -= n
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"database/sql"
+	"strings"
+)
+
+func (s *SQL) GetAllAPIKeys() ([]models.APIKey, error) {
+	rows, err := s.db.Query("SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// CreateAPIKey stores a new key by its hash.
+func (s *SQL) CreateAPIKey(name, prefix, hash string, scopes []string) (models.APIKey, error) {
+	createdAt := nowUTC()
+
+	id, err := s.db.Insert("INSERT INTO api_keys (name, prefix, key_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)",
+		name, prefix, hash, strings.Join(scopes, " "), createdAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return models.APIKey{
+		ID:        id,
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+func (s *SQL) GetAPIKeyByID(id int64) (models.APIKey, error) {
+	row := s.db.QueryRow("SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE id = ?", id)
+	key, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return models.APIKey{}, nil
+	}
+	return key, err
+}
+
+// GetActiveAPIKeyByHash returns the unrevoked key with the given hash, or a
+// zero key if there is none.
+func (s *SQL) GetActiveAPIKeyByHash(hash string) (models.APIKey, error) {
+	row := s.db.QueryRow("SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL", hash)
+	key, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return models.APIKey{}, nil
+	}
+	return key, err
+}
+
+// RevokeAPIKey marks a key as revoked so it is no longer accepted. Revoking
+// an already revoked key keeps the original revocation time.
+func (s *SQL) RevokeAPIKey(id int64) (models.APIKey, error) {
+	now := nowUTC()
+	_, err := s.db.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", now, id)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return s.GetAPIKeyByID(id)
+}
+
+// TouchAPIKey records that a key has just been used.
+func (s *SQL) TouchAPIKey(id int64) error {
+	_, err := s.db.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", nowUTC(), id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, scanTime(&key.CreatedAt), scanNullTime(&key.LastUsedAt), scanNullTime(&key.RevokedAt))
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	return key, nil
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"database/sql"
+)
+
+func (s *SQL) GetAllCustomers() ([]models.Customer, error) {
+	rows, err := s.db.Query("SELECT id, name, email, phone, created_at, updated_at FROM customers ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		var customer models.Customer
+		err := rows.Scan(&customer.ID, &customer.Name, &customer.Email, &customer.Phone, scanTime(&customer.CreatedAt), scanTime(&customer.UpdatedAt))
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	addresses, err := getAddresses(s.db, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range customers {
+		customers[i].Addresses = addressesOrEmpty(addresses[customers[i].ID])
+	}
+
+	return customers, nil
+}
+
+// CreateCustomer stores a customer together with their address book.
+func (s *SQL) CreateCustomer(customer models.Customer) (models.Customer, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Customer{}, err
+	}
+	defer tx.Rollback()
+
+	if err := checkEmailAvailable(tx, customer.Email, 0); err != nil {
+		return models.Customer{}, err
+	}
+
+	createdAt := nowUTC()
+	id, err := tx.Insert("INSERT INTO customers (name, email, phone, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		customer.Name, customer.Email, customer.Phone, createdAt, createdAt)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	customer.Addresses, err = insertAddresses(tx, id, customer.Addresses)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Customer{}, err
+	}
+
+	customer.ID = id
+	customer.CreatedAt = createdAt
+	customer.UpdatedAt = createdAt
+	return customer, nil
+}
+
+func (s *SQL) GetCustomerByID(id int64) (models.Customer, error) {
+	var customer models.Customer
+	err := s.db.QueryRow("SELECT id, name, email, phone, created_at, updated_at FROM customers WHERE id = ?", id).Scan(
+		&customer.ID, &customer.Name, &customer.Email, &customer.Phone, scanTime(&customer.CreatedAt), scanTime(&customer.UpdatedAt),
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Customer{}, nil
+		}
+		return models.Customer{}, err
+	}
+
+	addresses, err := getAddresses(s.db, "WHERE customer_id = ?", id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	customer.Addresses = addressesOrEmpty(addresses[id])
+
+	return customer, nil
+}
+
+// UpdateCustomer overwrites a customer and replaces their address book.
+func (s *SQL) UpdateCustomer(id int64, customer models.Customer) (models.Customer, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Customer{}, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT 1 FROM customers WHERE id = ? FOR UPDATE", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return models.Customer{}, nil
+	}
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if err := checkEmailAvailable(tx, customer.Email, id); err != nil {
+		return models.Customer{}, err
+	}
+
+	updatedAt := nowUTC()
+	_, err = tx.Exec("UPDATE customers SET name = ?, email = ?, phone = ?, updated_at = ? WHERE id = ?",
+		customer.Name, customer.Email, customer.Phone, updatedAt, id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	_, err = tx.Exec("DELETE FROM customer_addresses WHERE customer_id = ?", id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if _, err := insertAddresses(tx, id, customer.Addresses); err != nil {
+		return models.Customer{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Customer{}, err
+	}
+
+	return s.GetCustomerByID(id)
+}
+
+func (s *SQL) DeleteCustomer(id int64) (models.Customer, error) {
+	customer, err := s.GetCustomerByID(id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if customer.ID == 0 {
+		return models.Customer{}, nil
+	}
+
+	_, err = s.db.Exec("DELETE FROM customers WHERE id = ?", id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	return customer, nil
+}
+
+// checkEmailAvailable fails with ErrDuplicateEmail if a customer other than
+// exceptID already uses email, ignoring case.
+func checkEmailAvailable(tx *transaction, email string, exceptID int64) error {
+	var exists int
+	err := tx.QueryRow("SELECT 1 FROM customers WHERE LOWER(email) = LOWER(?) AND id <> ?", email, exceptID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrDuplicateEmail
+}
+
+func insertAddresses(tx *transaction, customerID int64, addresses []models.Address) ([]models.Address, error) {
+	saved := []models.Address{}
+	for _, address := range addresses {
+		id, err := tx.Insert("INSERT INTO customer_addresses (customer_id, label, line1, line2, city, province, postal_code, country) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			customerID, address.Label, address.Line1, address.Line2, address.City, address.Province, address.PostalCode, address.Country)
+		if err != nil {
+			return nil, err
+		}
+		address.ID = id
+		saved = append(saved, address)
+	}
+	return saved, nil
+}
+
+// getAddresses returns saved addresses keyed by customer ID.
+func getAddresses(db *database, where string, args ...interface{}) (map[int64][]models.Address, error) {
+	rows, err := db.Query("SELECT id, customer_id, label, line1, line2, city, province, postal_code, country FROM customer_addresses "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make(map[int64][]models.Address)
+	for rows.Next() {
+		var customerID int64
+		var address models.Address
+		err := rows.Scan(&address.ID, &customerID, &address.Label, &address.Line1, &address.Line2, &address.City, &address.Province, &address.PostalCode, &address.Country)
+		if err != nil {
+			return nil, err
+		}
+		addresses[customerID] = append(addresses[customerID], address)
+	}
+
+	return addresses, rows.Err()
+}
+
+func addressesOrEmpty(addresses []models.Address) []models.Address {
+	if addresses == nil {
+		return []models.Address{}
+	}
+	return addresses
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the flavour of SQL spoken by the database behind a SQL store.
+// Queries in this package are written for MySQL and rewritten for the other
+// dialects as they run.
+type Dialect string
+
+const (
+	MySQL Dialect = "mysql"
+	// SQLite has no row locks, so FOR UPDATE is dropped. The database must be
+	// opened with _txlock=immediate instead: every transaction then takes the
+	// write lock when it begins, which serializes the read-modify-write
+	// sequences that FOR UPDATE protects on MySQL.
+	SQLite Dialect = "sqlite"
+	// Postgres numbers parameters $1, $2, ... rather than using ?, and its
+	// drivers have no LastInsertId, so inserts return the new ID with
+	// RETURNING instead.
+	Postgres Dialect = "postgres"
+)
+
+var forUpdate = regexp.MustCompile(`\s+FOR UPDATE\b`)
+
+// rebind rewrites a MySQL query for the dialect.
+func (d Dialect) rebind(query string) string {
+	switch d {
+	case SQLite:
+		return forUpdate.ReplaceAllString(query, "")
+	case Postgres:
+		return numberParams(query)
+	default:
+		return query
+	}
+}
+
+// onConflictUpdate returns the clause that turns an INSERT into an upsert:
+// when a row with the same key exists, its columns are set to the values
+// being inserted instead.
+func (d Dialect) onConflictUpdate(key string, columns ...string) string {
+	set := make([]string, len(columns))
+	if d == MySQL {
+		for i, column := range columns {
+			set[i] = column + " = VALUES(" + column + ")"
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+	}
+	for i, column := range columns {
+		set[i] = column + " = excluded." + column
+	}
+	return " ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+// numberParams replaces every ? outside a quoted string with $1, $2, ...
+func numberParams(query string) string {
+	var b strings.Builder
+	var quote byte
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// runner is implemented by both *sql.DB and *sql.Tx.
+type runner interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// conn runs queries rewritten for its dialect, with time.Time arguments
+// bound as stored timestamps.
+type conn struct {
+	r       runner
+	dialect Dialect
+}
+
+func (c conn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.r.Exec(c.dialect.rebind(query), c.dialect.bindArgs(args)...)
+}
+
+func (c conn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.r.Query(c.dialect.rebind(query), c.dialect.bindArgs(args)...)
+}
+
+func (c conn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.r.QueryRow(c.dialect.rebind(query), c.dialect.bindArgs(args)...)
+}
+
+// Insert runs an INSERT into a table with an id column and returns the ID of
+// the new row.
+func (c conn) Insert(query string, args ...interface{}) (int64, error) {
+	if c.dialect == Postgres {
+		var id int64
+		err := c.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := c.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// database runs queries on a connection pool.
+type database struct {
+	conn
+	db *sql.DB
+}
+
+func newDatabase(db *sql.DB, dialect Dialect) *database {
+	return &database{conn: conn{r: db, dialect: dialect}, db: db}
+}
+
+func (d *database) Begin() (*transaction, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{conn: conn{r: tx, dialect: d.dialect}, tx: tx}, nil
+}
+
+// transaction runs queries in a transaction.
+type transaction struct {
+	conn
+	tx *sql.Tx
+}
+
+func (t *transaction) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *transaction) Rollback() error {
+	return t.tx.Rollback()
+}
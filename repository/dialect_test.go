@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{MySQL, "SELECT id FROM products WHERE id = ? FOR UPDATE", "SELECT id FROM products WHERE id = ? FOR UPDATE"},
+		{SQLite, "SELECT id FROM products WHERE id = ? FOR UPDATE", "SELECT id FROM products WHERE id = ?"},
+		{SQLite, "SELECT w.id\n\t\tFROM warehouses w\n\t\tFOR UPDATE", "SELECT w.id\n\t\tFROM warehouses w"},
+		{Postgres, "UPDATE products SET stock = stock - ?, sold = sold + ? WHERE id = ?", "UPDATE products SET stock = stock - $1, sold = sold + $2 WHERE id = $3"},
+		{Postgres, "SELECT 1 FROM t WHERE a = '?' AND b = ?", "SELECT 1 FROM t WHERE a = '?' AND b = $1"},
+		{Postgres, "SELECT id FROM products WHERE id = ? FOR UPDATE", "SELECT id FROM products WHERE id = $1 FOR UPDATE"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.rebind(tt.query); got != tt.want {
+			t.Errorf("%s: rebind(%q) = %q, want %q", tt.dialect, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestOnConflictUpdate(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{MySQL, " ON DUPLICATE KEY UPDATE rate = VALUES(rate), updated_at = VALUES(updated_at)"},
+		{SQLite, " ON CONFLICT (currency) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at"},
+		{Postgres, " ON CONFLICT (currency) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.onConflictUpdate("currency", "rate", "updated_at"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestBindArgs(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	at := time.Date(2024, 5, 1, 15, 30, 0, 0, jakarta)
+	var none *time.Time
+
+	for _, d := range []Dialect{MySQL, SQLite} {
+		got := d.bindArgs([]interface{}{at, &at, none, int64(1)})
+		if got[0] != "2024-05-01 08:30:00" || got[1] != "2024-05-01 08:30:00" || got[2] != nil || got[3] != int64(1) {
+			t.Errorf("%s: got %v", d, got)
+		}
+	}
+
+	got := Postgres.bindArgs([]interface{}{at, &at, none, int64(1)})
+	for i := 0; i < 2; i++ {
+		if bound, ok := got[i].(time.Time); !ok || !bound.Equal(at) || bound.Location() != time.UTC {
+			t.Errorf("postgres: argument %d: got %#v, want %v in UTC", i, got[i], at)
+		}
+	}
+	if got[2] != nil || got[3] != int64(1) {
+		t.Errorf("postgres: got %v", got)
+	}
+}
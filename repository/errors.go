@@ -0,0 +1,31 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrProductNotFound is returned when a referenced product does not exist.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrInsufficientStock is returned when a stock change would drive stock below zero.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrCustomerNotFound is returned when a referenced customer does not exist.
+	ErrCustomerNotFound = errors.New("customer not found")
+	// ErrDuplicateEmail is returned when another customer already uses the email address.
+	ErrDuplicateEmail = errors.New("email already in use")
+	// ErrOrderNotFound is returned when a referenced order does not exist.
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrOrderNotPending is returned when changing an order that is no longer pending.
+	ErrOrderNotPending = errors.New("order is not pending")
+	// ErrWarehouseNotFound is returned when a referenced warehouse does not exist.
+	ErrWarehouseNotFound = errors.New("warehouse not found")
+	// ErrWarehouseNotEmpty is returned when deleting a warehouse that still holds stock.
+	ErrWarehouseNotEmpty = errors.New("warehouse still holds stock")
+	// ErrStockBelowWarehouses is returned when setting a product's stock below
+	// the units its warehouses hold.
+	ErrStockBelowWarehouses = errors.New("stock is below the stock held by warehouses")
+	// ErrMixedCurrencies is returned when an order names no currency and its
+	// products are priced in different ones.
+	ErrMixedCurrencies = errors.New("products are priced in different currencies")
+	// ErrNoPriceInCurrency is returned when a product on an order has no price
+	// in the order currency.
+	ErrNoPriceInCurrency = errors.New("product has no price in the order currency")
+)
@@ -0,0 +1,36 @@
+package repository
+
+import "api-productnorder/models"
+
+func (s *SQL) GetExchangeRates() ([]models.ExchangeRate, error) {
+	rows, err := s.db.Query("SELECT currency, rate, updated_at FROM exchange_rates ORDER BY currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, scanTime(&rate.UpdatedAt)); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// SetExchangeRate adds or replaces the rate of a currency. It is a single
+// upsert, so concurrent writers of a new currency cannot both insert it.
+func (s *SQL) SetExchangeRate(currency string, rate float64) (models.ExchangeRate, error) {
+	updatedAt := nowUTC()
+
+	_, err := s.db.Exec("INSERT INTO exchange_rates (currency, rate, updated_at) VALUES (?, ?, ?)"+s.db.dialect.onConflictUpdate("currency", "rate", "updated_at"),
+		currency, rate, updatedAt)
+	if err != nil {
+		return models.ExchangeRate{}, err
+	}
+
+	return models.ExchangeRate{Currency: currency, Rate: rate, UpdatedAt: updatedAt}, nil
+}
@@ -0,0 +1,635 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory implements Store in process memory, for tests and local demos. It
+// follows the SQL implementation's semantics, including its errors and
+// ordering. A single lock serialises every call, so each one is atomic the
+// way a database transaction is, and calls that can fail check everything
+// before changing anything.
+type Memory struct {
+	mu sync.Mutex
+
+	lastID map[string]int64
+
+	products       map[int64]*memoryProduct
+	adjustments    map[int64]models.StockAdjustment
+	warehouses     map[int64]models.Warehouse
+	warehouseStock map[warehouseProduct]int64
+	customers      map[int64]models.Customer
+	orders         map[int64]*memoryOrder
+	allocations    []memoryAllocation
+	apiKeys        map[int64]*memoryAPIKey
+	exchangeRates  map[string]models.ExchangeRate
+}
+
+type memoryProduct struct {
+	models.Product
+	lowStockAlerted bool
+}
+
+type warehouseProduct struct {
+	warehouseID int64
+	productID   int64
+}
+
+type memoryAPIKey struct {
+	models.APIKey
+	hash string
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		lastID:         make(map[string]int64),
+		products:       make(map[int64]*memoryProduct),
+		adjustments:    make(map[int64]models.StockAdjustment),
+		warehouses:     make(map[int64]models.Warehouse),
+		warehouseStock: make(map[warehouseProduct]int64),
+		customers:      make(map[int64]models.Customer),
+		orders:         make(map[int64]*memoryOrder),
+		apiKeys:        make(map[int64]*memoryAPIKey),
+		exchangeRates:  make(map[string]models.ExchangeRate),
+	}
+}
+
+// nextID returns the next auto-increment value for a table.
+func (m *Memory) nextID(table string) int64 {
+	m.lastID[table]++
+	return m.lastID[table]
+}
+
+func (m *Memory) GetAllProducts() ([]models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var products []models.Product
+	for _, id := range m.productIDs() {
+		product := m.productData(id)
+		products = append(products, models.Product(product))
+	}
+	return products, nil
+}
+
+func (m *Memory) GetLowStockProducts() ([]models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	products := []models.Product{}
+	for _, id := range m.productIDs() {
+		product := m.products[id].Product
+		if product.Stock < product.ReorderThreshold {
+			product.Prices = copyPrices(product.Prices)
+			products = append(products, product)
+		}
+	}
+	sort.SliceStable(products, func(i, j int) bool { return products[i].Stock < products[j].Stock })
+	return products, nil
+}
+
+func (m *Memory) CreateProduct(name string, price int64, currency string, prices []models.ProductPrice, stock int64, reorderThreshold int64, stockPolicy string) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := nowUTC()
+	product := models.Product{
+		ID:               m.nextID("products"),
+		Name:             name,
+		Price:            price,
+		Currency:         currency,
+		Prices:           copyPrices(prices),
+		Stock:            stock,
+		ReorderThreshold: reorderThreshold,
+		StockPolicy:      stockPolicy,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	m.products[product.ID] = &memoryProduct{Product: product}
+	return m.productData(product.ID), nil
+}
+
+func (m *Memory) GetProductByID(id int64) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.productData(id), nil
+}
+
+func (m *Memory) UpdateProduct(id int64, name string, price int64, currency string, prices []models.ProductPrice, stock int64, reorderThreshold int64, stockPolicy string) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[id]
+	if !ok {
+		return models.Product{}, nil
+	}
+	if stock < m.assignedStock(id) {
+		return models.Product{}, ErrStockBelowWarehouses
+	}
+
+	if stock >= reorderThreshold {
+		product.lowStockAlerted = false
+	}
+	product.Name = name
+	product.Price = price
+	product.Currency = currency
+	product.Prices = copyPrices(prices)
+	product.Stock = stock
+	product.ReorderThreshold = reorderThreshold
+	product.StockPolicy = stockPolicy
+	product.UpdatedAt = nowUTC()
+
+	return m.productData(id), nil
+}
+
+func (m *Memory) MarkLowStockAlerted(id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[id]
+	if !ok || product.lowStockAlerted || product.Stock >= product.ReorderThreshold {
+		return false, nil
+	}
+	product.lowStockAlerted = true
+	return true, nil
+}
+
+func (m *Memory) DeleteProduct(id int64) (models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product := m.productData(id)
+	if product.ID == 0 {
+		return models.Product{}, nil
+	}
+
+	delete(m.products, id)
+	for key := range m.warehouseStock {
+		if key.productID == id {
+			delete(m.warehouseStock, key)
+		}
+	}
+	for adjustmentID, adjustment := range m.adjustments {
+		if adjustment.ProductID == id {
+			delete(m.adjustments, adjustmentID)
+		}
+	}
+	return product, nil
+}
+
+func (m *Memory) AdjustStock(productID int64, warehouseID int64, delta int64, reason string) (models.StockAdjustment, models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product, ok := m.products[productID]
+	if !ok {
+		return models.StockAdjustment{}, models.Product{}, ErrProductNotFound
+	}
+	if product.Stock+delta < 0 {
+		return models.StockAdjustment{}, models.Product{}, ErrInsufficientStock
+	}
+	// Removing unassigned stock must not eat into stock held by warehouses.
+	if warehouseID == 0 && delta < 0 && product.Stock+delta < m.assignedStock(productID) {
+		return models.StockAdjustment{}, models.Product{}, ErrInsufficientStock
+	}
+
+	key := warehouseProduct{warehouseID: warehouseID, productID: productID}
+	if warehouseID != 0 {
+		if _, ok := m.warehouses[warehouseID]; !ok {
+			return models.StockAdjustment{}, models.Product{}, ErrWarehouseNotFound
+		}
+		stock, ok := m.warehouseStock[key]
+		if (ok && stock+delta < 0) || (!ok && delta < 0) {
+			return models.StockAdjustment{}, models.Product{}, ErrInsufficientStock
+		}
+	}
+
+	now := nowUTC()
+	product.Stock += delta
+	product.UpdatedAt = now
+	if warehouseID != 0 {
+		m.warehouseStock[key] += delta
+	}
+
+	adjustment := models.StockAdjustment{
+		ID:          m.nextID("stock_adjustments"),
+		ProductID:   productID,
+		WarehouseID: warehouseID,
+		Delta:       delta,
+		Reason:      reason,
+		CreatedAt:   now,
+	}
+	m.adjustments[adjustment.ID] = adjustment
+
+	if delta > 0 {
+		adjustment.BackordersAllocated = m.allocateBackorders(productID, warehouseID, now)
+	}
+	if product.Stock >= product.ReorderThreshold {
+		product.lowStockAlerted = false
+	}
+
+	return adjustment, m.productData(productID), nil
+}
+
+// allocateBackorders fills backordered order lines for a product, oldest
+// order first, from the given warehouse or from unassigned stock when
+// warehouseID is zero. It returns the number of units allocated.
+func (m *Memory) allocateBackorders(productID int64, warehouseID int64, now time.Time) int64 {
+	product := m.products[productID]
+
+	var available int64
+	if warehouseID != 0 {
+		available = m.warehouseStock[warehouseProduct{warehouseID: warehouseID, productID: productID}]
+	} else {
+		available = product.Stock - m.assignedStock(productID)
+	}
+
+	var allocated int64
+	for _, orderID := range m.orderIDs() {
+		if available <= 0 {
+			break
+		}
+
+		line, ok := m.orders[orderID].lines[productID]
+		if !ok || line.backordered <= 0 {
+			continue
+		}
+
+		n := line.backordered
+		if n > available {
+			n = available
+		}
+		line.backordered -= n
+		line.updatedAt = now
+
+		if warehouseID != 0 {
+			m.recordAllocation(orderID, productID, models.Allocation{WarehouseID: warehouseID, Quantity: n}, now)
+		}
+
+		available -= n
+		allocated += n
+	}
+
+	product.Stock -= allocated
+	return allocated
+}
+
+// productIDs returns the IDs of every product in ascending order.
+func (m *Memory) productIDs() []int64 {
+	ids := make([]int64, 0, len(m.products))
+	for id := range m.products {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// productData returns a copy of a product with its warehouse stock, or a
+// zero product if there is none with the ID.
+func (m *Memory) productData(id int64) models.Product {
+	product, ok := m.products[id]
+	if !ok {
+		return models.Product{}
+	}
+	data := product.Product
+	data.Prices = copyPrices(data.Prices)
+	data.Warehouses = m.productWarehouseStock(id)
+	return data
+}
+
+// copyPrices copies a price list in currency order, as SQL lists it.
+func copyPrices(prices []models.ProductPrice) []models.ProductPrice {
+	if len(prices) == 0 {
+		return nil
+	}
+	copied := append([]models.ProductPrice{}, prices...)
+	sort.Slice(copied, func(i, j int) bool { return copied[i].Currency < copied[j].Currency })
+	return copied
+}
+
+// productWarehouseStock returns a product's stock at each warehouse that has
+// held it, in warehouse priority order.
+func (m *Memory) productWarehouseStock(productID int64) []models.WarehouseStock {
+	var stocks []models.WarehouseStock
+	for key, stock := range m.warehouseStock {
+		if key.productID != productID {
+			continue
+		}
+		warehouse := m.warehouses[key.warehouseID]
+		stocks = append(stocks, models.WarehouseStock{
+			WarehouseID: warehouse.ID,
+			Code:        warehouse.Code,
+			Name:        warehouse.Name,
+			Stock:       stock,
+		})
+	}
+	sort.Slice(stocks, func(i, j int) bool {
+		a, b := m.warehouses[stocks[i].WarehouseID], m.warehouses[stocks[j].WarehouseID]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.ID < b.ID
+	})
+	return stocks
+}
+
+// assignedStock returns the units of a product held by warehouses.
+func (m *Memory) assignedStock(productID int64) int64 {
+	var total int64
+	for key, stock := range m.warehouseStock {
+		if key.productID == productID {
+			total += stock
+		}
+	}
+	return total
+}
+
+func (m *Memory) GetAllWarehouses() ([]models.Warehouse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	warehouses := []models.Warehouse{}
+	for _, warehouse := range m.warehouses {
+		warehouses = append(warehouses, warehouse)
+	}
+	sort.Slice(warehouses, func(i, j int) bool {
+		if warehouses[i].Priority != warehouses[j].Priority {
+			return warehouses[i].Priority < warehouses[j].Priority
+		}
+		return warehouses[i].ID < warehouses[j].ID
+	})
+	return warehouses, nil
+}
+
+func (m *Memory) CreateWarehouse(warehouse models.Warehouse) (models.Warehouse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := nowUTC()
+	warehouse.ID = m.nextID("warehouses")
+	warehouse.CreatedAt = now
+	warehouse.UpdatedAt = now
+	m.warehouses[warehouse.ID] = warehouse
+	return warehouse, nil
+}
+
+func (m *Memory) GetWarehouseByID(id int64) (models.Warehouse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.warehouses[id], nil
+}
+
+func (m *Memory) UpdateWarehouse(id int64, warehouse models.Warehouse) (models.Warehouse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.warehouses[id]
+	if !ok {
+		return models.Warehouse{}, nil
+	}
+
+	warehouse.ID = id
+	warehouse.CreatedAt = existing.CreatedAt
+	warehouse.UpdatedAt = nowUTC()
+	m.warehouses[id] = warehouse
+	return warehouse, nil
+}
+
+func (m *Memory) DeleteWarehouse(id int64) (models.Warehouse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	warehouse, ok := m.warehouses[id]
+	if !ok {
+		return models.Warehouse{}, nil
+	}
+
+	var stock int64
+	for key, n := range m.warehouseStock {
+		if key.warehouseID == id {
+			stock += n
+		}
+	}
+	if stock > 0 {
+		return models.Warehouse{}, ErrWarehouseNotEmpty
+	}
+
+	delete(m.warehouses, id)
+	for key := range m.warehouseStock {
+		if key.warehouseID == id {
+			delete(m.warehouseStock, key)
+		}
+	}
+	return warehouse, nil
+}
+
+func (m *Memory) GetAllCustomers() ([]models.Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	customers := []models.Customer{}
+	for _, customer := range m.customers {
+		customers = append(customers, copyCustomer(customer))
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+	return customers, nil
+}
+
+func (m *Memory) CreateCustomer(customer models.Customer) (models.Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.emailAvailable(customer.Email, 0) {
+		return models.Customer{}, ErrDuplicateEmail
+	}
+
+	now := nowUTC()
+	customer.ID = m.nextID("customers")
+	customer.CreatedAt = now
+	customer.UpdatedAt = now
+	customer.Addresses = m.saveAddresses(customer.Addresses)
+	m.customers[customer.ID] = customer
+	return copyCustomer(customer), nil
+}
+
+func (m *Memory) GetCustomerByID(id int64) (models.Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	customer, ok := m.customers[id]
+	if !ok {
+		return models.Customer{}, nil
+	}
+	return copyCustomer(customer), nil
+}
+
+func (m *Memory) UpdateCustomer(id int64, customer models.Customer) (models.Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.customers[id]
+	if !ok {
+		return models.Customer{}, nil
+	}
+	if !m.emailAvailable(customer.Email, id) {
+		return models.Customer{}, ErrDuplicateEmail
+	}
+
+	customer.ID = id
+	customer.CreatedAt = existing.CreatedAt
+	customer.UpdatedAt = nowUTC()
+	customer.Addresses = m.saveAddresses(customer.Addresses)
+	m.customers[id] = customer
+	return copyCustomer(customer), nil
+}
+
+func (m *Memory) DeleteCustomer(id int64) (models.Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	customer, ok := m.customers[id]
+	if !ok {
+		return models.Customer{}, nil
+	}
+
+	delete(m.customers, id)
+	// Orders outlive the customer who placed them.
+	for _, order := range m.orders {
+		if order.customerID == id {
+			order.customerID = 0
+		}
+	}
+	return copyCustomer(customer), nil
+}
+
+// emailAvailable reports whether no customer other than exceptID uses email.
+// Emails compare case-insensitively, as under MySQL's default collation.
+func (m *Memory) emailAvailable(email string, exceptID int64) bool {
+	for id, customer := range m.customers {
+		if id != exceptID && strings.EqualFold(customer.Email, email) {
+			return false
+		}
+	}
+	return true
+}
+
+// saveAddresses gives each address in an address book a new ID.
+func (m *Memory) saveAddresses(addresses []models.Address) []models.Address {
+	saved := []models.Address{}
+	for _, address := range addresses {
+		address.ID = m.nextID("customer_addresses")
+		saved = append(saved, address)
+	}
+	return saved
+}
+
+func copyCustomer(customer models.Customer) models.Customer {
+	customer.Addresses = append([]models.Address{}, customer.Addresses...)
+	return customer
+}
+
+func (m *Memory) GetAllAPIKeys() ([]models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []models.APIKey{}
+	for _, key := range m.apiKeys {
+		keys = append(keys, copyAPIKey(key.APIKey))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (m *Memory) CreateAPIKey(name, prefix, hash string, scopes []string) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := models.APIKey{
+		ID:        m.nextID("api_keys"),
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: nowUTC(),
+	}
+	m.apiKeys[key.ID] = &memoryAPIKey{APIKey: copyAPIKey(key), hash: hash}
+	return key, nil
+}
+
+func (m *Memory) GetActiveAPIKeyByHash(hash string) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.apiKeys {
+		if key.hash == hash && key.RevokedAt == nil {
+			return copyAPIKey(key.APIKey), nil
+		}
+	}
+	return models.APIKey{}, nil
+}
+
+func (m *Memory) RevokeAPIKey(id int64) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.apiKeys[id]
+	if !ok {
+		return models.APIKey{}, nil
+	}
+	if key.RevokedAt == nil {
+		now := nowUTC()
+		key.RevokedAt = &now
+	}
+	return copyAPIKey(key.APIKey), nil
+}
+
+func (m *Memory) TouchAPIKey(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if key, ok := m.apiKeys[id]; ok {
+		now := nowUTC()
+		key.LastUsedAt = &now
+	}
+	return nil
+}
+
+func (m *Memory) GetExchangeRates() ([]models.ExchangeRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rates := []models.ExchangeRate{}
+	for _, rate := range m.exchangeRates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Currency < rates[j].Currency })
+	return rates, nil
+}
+
+func (m *Memory) SetExchangeRate(currency string, rate float64) (models.ExchangeRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exchangeRate := models.ExchangeRate{Currency: currency, Rate: rate, UpdatedAt: nowUTC()}
+	m.exchangeRates[currency] = exchangeRate
+	return exchangeRate, nil
+}
+
+func copyAPIKey(key models.APIKey) models.APIKey {
+	key.Scopes = append([]string{}, key.Scopes...)
+	if key.LastUsedAt != nil {
+		lastUsedAt := *key.LastUsedAt
+		key.LastUsedAt = &lastUsedAt
+	}
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		key.RevokedAt = &revokedAt
+	}
+	return key
+}
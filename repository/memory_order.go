@@ -0,0 +1,486 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type memoryOrder struct {
+	id         int64
+	customerID int64
+	currency   string
+	status     string
+	createdAt  time.Time
+	updatedAt  time.Time
+	lines      map[int64]*memoryLine
+	addresses  map[string]models.OrderAddress
+}
+
+type memoryLine struct {
+	productName string
+	quantity    int64
+	backordered int64
+	unitPrice   int64
+	createdAt   time.Time
+	updatedAt   time.Time
+}
+
+type memoryAllocation struct {
+	orderID     int64
+	productID   int64
+	warehouseID int64
+	quantity    int64
+}
+
+func (m *Memory) GetOrders() ([]models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listOrders(0), nil
+}
+
+func (m *Memory) GetOrdersByCustomer(customerID int64) ([]models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listOrders(customerID), nil
+}
+
+// listOrders returns the orders of a customer, or every order when
+// customerID is zero, ordered by ID. Like the SQL listing it leaves out
+// orders without any line.
+func (m *Memory) listOrders(customerID int64) []models.Order {
+	orders := []models.Order{}
+	for _, id := range m.orderIDs() {
+		order := m.orders[id]
+		if customerID != 0 && order.customerID != customerID {
+			continue
+		}
+		view := m.orderView(order)
+		if len(view.Lines) == 0 {
+			continue
+		}
+		orders = append(orders, view)
+	}
+	return orders
+}
+
+func (m *Memory) CreateOrder(order NewOrder) (models.Order, []models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if order.CustomerID != 0 {
+		if _, ok := m.customers[order.CustomerID]; !ok {
+			return models.Order{}, nil, ErrCustomerNotFound
+		}
+	}
+
+	items := mergeItems(order.Items)
+	products := make([]models.Product, len(items))
+	for i, item := range items {
+		product, ok := m.products[item.ProductID]
+		if !ok {
+			return models.Order{}, nil, fmt.Errorf("%w: %d", ErrProductNotFound, item.ProductID)
+		}
+		products[i] = product.Product
+	}
+	currency, err := orderCurrency(order.Currency, products)
+	if err != nil {
+		return models.Order{}, nil, err
+	}
+	for _, item := range items {
+		if _, _, err := reserve(m.products[item.ProductID].Product, item.Quantity); err != nil {
+			return models.Order{}, nil, fmt.Errorf("%w: %d", err, item.ProductID)
+		}
+	}
+
+	now := nowUTC()
+	stored := &memoryOrder{
+		id:         m.nextID("orders"),
+		customerID: order.CustomerID,
+		currency:   currency,
+		status:     models.OrderStatusPending,
+		createdAt:  now,
+		updatedAt:  now,
+		lines:      make(map[int64]*memoryLine),
+		addresses:  make(map[string]models.OrderAddress),
+	}
+	if order.ShippingAddress != nil {
+		stored.addresses[addressShipping] = *order.ShippingAddress
+	}
+	if order.BillingAddress != nil {
+		stored.addresses[addressBilling] = *order.BillingAddress
+	}
+	m.orders[stored.id] = stored
+
+	var updated []models.Product
+	for _, item := range items {
+		product := m.products[item.ProductID]
+		allocated, backordered, _ := reserve(product.Product, item.Quantity)
+		unitPrice, _ := product.PriceIn(currency)
+
+		product.Stock -= allocated
+		product.Sold += item.Quantity
+		product.UpdatedAt = now
+
+		stored.lines[item.ProductID] = &memoryLine{
+			productName: product.Name,
+			quantity:    item.Quantity,
+			backordered: backordered,
+			unitPrice:   unitPrice,
+			createdAt:   now,
+			updatedAt:   now,
+		}
+
+		if allocated > 0 {
+			m.allocateWarehouseStock(stored.id, item.ProductID, allocated, order.Allocation, now)
+		}
+
+		updated = append(updated, product.Product)
+	}
+
+	return m.orderView(stored), updated, nil
+}
+
+func (m *Memory) GetOrderByID(id int64) (models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[id]
+	if !ok {
+		return models.Order{}, nil
+	}
+	return m.orderView(order), nil
+}
+
+func (m *Memory) DeleteOrderByID(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.orders, id)
+	kept := m.allocations[:0]
+	for _, allocation := range m.allocations {
+		if allocation.orderID != id {
+			kept = append(kept, allocation)
+		}
+	}
+	m.allocations = kept
+	return nil
+}
+
+func (m *Memory) UpdateOrderItems(orderID int64, items []OrderItem, allocation models.AllocationRequest) ([]models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	if order.status != models.OrderStatusPending {
+		return nil, ErrOrderNotPending
+	}
+
+	desired := make(map[int64]int64)
+	for _, item := range items {
+		desired[item.ProductID] += item.Quantity
+	}
+
+	var productIDs []int64
+	for id := range order.lines {
+		productIDs = append(productIDs, id)
+	}
+	for id := range desired {
+		if _, ok := order.lines[id]; !ok {
+			productIDs = append(productIDs, id)
+		}
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	type change struct {
+		productID int64
+		old       memoryLine
+		quantity  int64
+	}
+	var changes []change
+	for _, productID := range productIDs {
+		var old memoryLine
+		if line, ok := order.lines[productID]; ok {
+			old = *line
+		}
+		quantity := desired[productID]
+		delta := quantity - old.quantity
+		if delta == 0 {
+			continue
+		}
+
+		product, ok := m.products[productID]
+		if !ok && delta < 0 {
+			// Lines of deleted products can still be reduced or removed.
+			changes = append(changes, change{productID: productID, old: old, quantity: quantity})
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+		}
+		if _, ok := product.PriceIn(order.currency); old.quantity == 0 && !ok {
+			return nil, fmt.Errorf("%w: %d", ErrNoPriceInCurrency, productID)
+		}
+		if delta > 0 {
+			if _, _, err := reserve(product.Product, delta); err != nil {
+				return nil, fmt.Errorf("%w: %d", err, productID)
+			}
+		}
+		changes = append(changes, change{productID: productID, old: old, quantity: quantity})
+	}
+
+	now := nowUTC()
+	var updated []models.Product
+	for _, c := range changes {
+		product, exists := m.products[c.productID]
+		if !exists {
+			// A line of a deleted product is reduced against a stand-in,
+			// since there is no stock left to return the units to.
+			product = &memoryProduct{}
+		}
+		delta := c.quantity - c.old.quantity
+
+		backordered := c.old.backordered
+		if delta > 0 {
+			allocated, short, _ := reserve(product.Product, delta)
+			product.Stock -= allocated
+			if allocated > 0 {
+				m.allocateWarehouseStock(orderID, c.productID, allocated, allocation, now)
+			}
+			backordered += short
+		} else {
+			released := -delta
+			cancelled := released
+			if cancelled > backordered {
+				cancelled = backordered
+			}
+			returned := released - cancelled
+			backordered -= cancelled
+
+			if product.Stock+returned >= product.ReorderThreshold {
+				product.lowStockAlerted = false
+			}
+			product.Stock += returned
+			m.releaseWarehouseAllocations(orderID, c.productID, returned)
+		}
+		product.Sold += delta
+		product.UpdatedAt = now
+
+		switch {
+		case c.quantity == 0:
+			delete(order.lines, c.productID)
+		case c.old.quantity == 0:
+			unitPrice, _ := product.PriceIn(order.currency)
+			order.lines[c.productID] = &memoryLine{productName: product.Name, quantity: c.quantity, backordered: backordered, unitPrice: unitPrice, createdAt: now, updatedAt: now}
+		default:
+			line := order.lines[c.productID]
+			line.quantity = c.quantity
+			line.backordered = backordered
+			line.updatedAt = now
+		}
+
+		if exists {
+			updated = append(updated, product.Product)
+		}
+	}
+
+	order.updatedAt = now
+	return updated, nil
+}
+
+func (m *Memory) UpdateOrderAddresses(orderID int64, shipping, billing *models.OrderAddress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderID]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if order.status != models.OrderStatusPending {
+		return ErrOrderNotPending
+	}
+
+	if shipping != nil {
+		order.addresses[addressShipping] = *shipping
+	}
+	if billing != nil {
+		order.addresses[addressBilling] = *billing
+	}
+	order.updatedAt = nowUTC()
+	return nil
+}
+
+func (m *Memory) GetSalesByCurrency() ([]models.SalesTotal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totals := make(map[string]*models.SalesTotal)
+	var currencies []string
+	for _, order := range m.orders {
+		if len(order.lines) == 0 {
+			continue
+		}
+		total, ok := totals[order.currency]
+		if !ok {
+			total = &models.SalesTotal{Currency: order.currency}
+			totals[order.currency] = total
+			currencies = append(currencies, order.currency)
+		}
+		total.Orders++
+		for _, line := range order.lines {
+			total.Total += line.quantity * line.unitPrice
+		}
+	}
+	sort.Strings(currencies)
+
+	sales := []models.SalesTotal{}
+	for _, currency := range currencies {
+		sales = append(sales, *totals[currency])
+	}
+	return sales, nil
+}
+
+// orderIDs returns the IDs of every order in ascending order.
+func (m *Memory) orderIDs() []int64 {
+	ids := make([]int64, 0, len(m.orders))
+	for id := range m.orders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// orderView builds the API representation of an order. Lines for products
+// that no longer exist are left out, as the SQL join does.
+func (m *Memory) orderView(order *memoryOrder) models.Order {
+	id := order.id
+	createdAt := order.createdAt
+	updatedAt := order.updatedAt
+	view := models.Order{
+		ID:        &id,
+		Currency:  order.currency,
+		Status:    order.status,
+		CreatedAt: &createdAt,
+		UpdatedAt: &updatedAt,
+	}
+	if order.customerID != 0 {
+		customerID := order.customerID
+		view.CustomerID = &customerID
+	}
+	if address, ok := order.addresses[addressShipping]; ok {
+		view.ShippingAddress = &address
+	}
+	if address, ok := order.addresses[addressBilling]; ok {
+		view.BillingAddress = &address
+	}
+
+	productIDs := make([]int64, 0, len(order.lines))
+	for productID := range order.lines {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	for _, productID := range productIDs {
+		line := order.lines[productID]
+		product := models.Product{
+			ID:        productID,
+			Name:      line.productName,
+			Price:     line.unitPrice,
+			Currency:  order.currency,
+			CreatedAt: line.createdAt,
+			UpdatedAt: line.updatedAt,
+		}
+		if stored, ok := m.products[productID]; ok {
+			product = stored.Product
+		}
+		view.Lines = append(view.Lines, models.NewOrderLine(product, line.quantity, line.unitPrice, line.backordered, m.orderAllocations(order.id, productID)))
+	}
+	return view
+}
+
+// orderAllocations totals the units of an order line shipped from each
+// warehouse, in warehouse ID order.
+func (m *Memory) orderAllocations(orderID, productID int64) []models.Allocation {
+	totals := make(map[int64]int64)
+	for _, allocation := range m.allocations {
+		if allocation.orderID == orderID && allocation.productID == productID {
+			totals[allocation.warehouseID] += allocation.quantity
+		}
+	}
+
+	var allocations []models.Allocation
+	for warehouseID, quantity := range totals {
+		allocations = append(allocations, models.Allocation{WarehouseID: warehouseID, Quantity: quantity})
+	}
+	sort.Slice(allocations, func(i, j int) bool { return allocations[i].WarehouseID < allocations[j].WarehouseID })
+	return allocations
+}
+
+// allocateWarehouseStock plans and records the warehouses that ship quantity
+// units of a product for an order.
+func (m *Memory) allocateWarehouseStock(orderID, productID, quantity int64, req models.AllocationRequest, now time.Time) []models.Allocation {
+	var candidates []warehouseCandidate
+	for key, stock := range m.warehouseStock {
+		if key.productID != productID || stock <= 0 {
+			continue
+		}
+		warehouse := m.warehouses[key.warehouseID]
+		candidates = append(candidates, warehouseCandidate{
+			WarehouseID: warehouse.ID,
+			Stock:       stock,
+			Latitude:    warehouse.Latitude,
+			Longitude:   warehouse.Longitude,
+			Priority:    warehouse.Priority,
+		})
+	}
+
+	allocations := planAllocation(req, candidates, quantity)
+	for _, allocation := range allocations {
+		m.recordAllocation(orderID, productID, allocation, now)
+	}
+	return allocations
+}
+
+// recordAllocation takes allocated units out of a warehouse and links them to an order line.
+func (m *Memory) recordAllocation(orderID, productID int64, allocation models.Allocation, now time.Time) {
+	m.warehouseStock[warehouseProduct{warehouseID: allocation.WarehouseID, productID: productID}] -= allocation.Quantity
+	m.allocations = append(m.allocations, memoryAllocation{
+		orderID:     orderID,
+		productID:   productID,
+		warehouseID: allocation.WarehouseID,
+		quantity:    allocation.Quantity,
+	})
+}
+
+// releaseWarehouseAllocations returns up to quantity units of an order line to
+// the warehouses they were allocated from, most recent allocation first.
+func (m *Memory) releaseWarehouseAllocations(orderID, productID, quantity int64) {
+	for i := len(m.allocations) - 1; i >= 0 && quantity > 0; i-- {
+		allocation := &m.allocations[i]
+		if allocation.orderID != orderID || allocation.productID != productID {
+			continue
+		}
+
+		n := allocation.quantity
+		if n > quantity {
+			n = quantity
+		}
+		// Units of a deleted product have no warehouse stock to go back to,
+		// as in the SQL store where its rows are gone.
+		if _, ok := m.products[productID]; ok {
+			m.warehouseStock[warehouseProduct{warehouseID: allocation.warehouseID, productID: productID}] += n
+		}
+		allocation.quantity -= n
+		quantity -= n
+
+		if allocation.quantity == 0 {
+			m.allocations = append(m.allocations[:i], m.allocations[i+1:]...)
+		}
+	}
+}
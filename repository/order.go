@@ -3,95 +3,372 @@ package repository
 import (
 	"api-productnorder/models"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 )
 
 // GetOrders retrieves a list of orders with their related products.
-func GetOrders(db *sql.DB) ([]models.Order, error) {
+func (s *SQL) GetOrders() ([]models.Order, error) {
+	return queryOrders(s.db, "", nil)
+}
+
+// GetOrdersByCustomer retrieves the orders placed by a customer with their related products.
+func (s *SQL) GetOrdersByCustomer(customerID int64) ([]models.Order, error) {
+	return queryOrders(s.db, "WHERE o.customer_id = ?", []interface{}{customerID})
+}
+
+// queryOrders lists orders matching an optional WHERE clause on orders o,
+// ordered by order ID.
+func queryOrders(db *database, where string, args []interface{}) ([]models.Order, error) {
 	// Query to get orders
 	rows, err := db.Query(`
-		SELECT o.id, o.created_at, o.updated_at, p.id, p.name, p.price, op.quantity, p.stock, p.sold, p.created_at, p.updated_at
+		SELECT o.id, o.customer_id, o.currency, o.status, o.created_at, o.updated_at, op.quantity, op.backordered_quantity, op.unit_price, `+orderLineProductColumns+`
 		FROM orders o
 		JOIN order_products op ON o.id = op.order_id
-		JOIN products p ON op.product_id = p.id
-		ORDER BY o.id;
-	`)
+		LEFT JOIN products p ON op.product_id = p.id
+		`+where+`
+		ORDER BY o.id, op.product_id;
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	ordersMap := make(map[int64]*models.Order)
+	var orderIDs []int64
 
 	for rows.Next() {
 		var orderID int64
-		var orderCreatedAt, orderUpdatedAt string
-		var product models.Product
+		var customerID sql.NullInt64
+		var currency, orderStatus string
+		var orderCreatedAt, orderUpdatedAt time.Time
+		var line models.OrderLine
 
-		err := rows.Scan(&orderID, &orderCreatedAt, &orderUpdatedAt,
-			&product.ID, &product.Name, &product.Price, &product.Quantity,
-			&product.Stock, &product.Sold, &product.CreatedAt, &product.UpdatedAt)
-		if err != nil {
+		dest := append([]interface{}{&orderID, &customerID, &currency, &orderStatus, scanTime(&orderCreatedAt), scanTime(&orderUpdatedAt)}, orderLineDest(&line)...)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
+		line.LineTotal = line.Quantity * line.UnitPrice
 
 		// Check if order already exists in the map
 		if _, ok := ordersMap[orderID]; !ok {
 			ordersMap[orderID] = &models.Order{
-				ID:        &orderID,
-				CreatedAt: &orderCreatedAt,
-				UpdatedAt: &orderUpdatedAt,
-				Products:  []models.Product{},
+				ID:         &orderID,
+				CustomerID: nullInt64Ptr(customerID),
+				Currency:   currency,
+				Status:     orderStatus,
+				CreatedAt:  &orderCreatedAt,
+				UpdatedAt:  &orderUpdatedAt,
+				Lines:      []models.OrderLine{},
 			}
+			orderIDs = append(orderIDs, orderID)
 		}
 
-		// Append product to the order
-		ordersMap[orderID].Products = append(ordersMap[orderID].Products, product)
+		ordersMap[orderID].Lines = append(ordersMap[orderID].Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Related rows are limited to the listed orders when a filter is applied.
+	relatedWhere := ""
+	if where != "" {
+		relatedWhere = "WHERE order_id IN (SELECT o.id FROM orders o " + where + ")"
+	}
+
+	allocations, err := getOrderAllocations(db, relatedWhere, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := getOrderAddresses(db, relatedWhere, args...)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert map to slice
-	var orders []models.Order
-	for _, order := range ordersMap {
+	// Convert map to slice, keeping the order of the query
+	orders := []models.Order{}
+	for _, orderID := range orderIDs {
+		order := ordersMap[orderID]
+		order.ShippingAddress = addresses[orderID][addressShipping]
+		order.BillingAddress = addresses[orderID][addressBilling]
+		for i := range order.Lines {
+			order.Lines[i].Allocations = allocations[[2]int64{orderID, order.Lines[i].Product.ID}]
+		}
 		orders = append(orders, *order)
 	}
 
 	return orders, nil
 }
 
-func CreateOrder(db *sql.DB, products []models.Product) (int64, error) {
-	// Simpan order ke dalam tabel orders
-	result, err := db.Exec("INSERT INTO orders (created_at, updated_at) VALUES (NOW(), NOW())")
+// orderLineProductColumns selects the product of an order line from
+// products p, in the order orderLineDest scans them. When the product has
+// been deleted, it is described by what the line kept of it in
+// order_products op and its order o.
+const orderLineProductColumns = `op.product_id, COALESCE(p.name, op.product_name), COALESCE(p.price, op.unit_price), COALESCE(p.currency, o.currency),
+	COALESCE(p.stock, 0), COALESCE(p.sold, 0), COALESCE(p.reorder_threshold, 0), COALESCE(p.stock_policy, ''),
+	COALESCE(p.created_at, op.created_at), COALESCE(p.updated_at, op.updated_at)`
+
+// orderLineDest returns the scan destinations for op.quantity,
+// op.backordered_quantity, op.unit_price and orderLineProductColumns.
+func orderLineDest(line *models.OrderLine) []interface{} {
+	product := &line.Product
+	return []interface{}{
+		&line.Quantity, &line.BackorderedQuantity, &line.UnitPrice,
+		&product.ID, &product.Name, &product.Price, &product.Currency, &product.Stock, &product.Sold,
+		&product.ReorderThreshold, &product.StockPolicy, scanTime(&product.CreatedAt), scanTime(&product.UpdatedAt),
+	}
+}
+
+func nullInt64Ptr(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+// OrderItem is a product and quantity requested in a new order.
+type OrderItem struct {
+	ProductID int64
+	Quantity  int64
+}
+
+// NewOrder is an order to be placed by CreateOrder. CustomerID is optional;
+// zero leaves the order without an owner.
+type NewOrder struct {
+	CustomerID      int64
+	Items           []OrderItem
+	Allocation      models.AllocationRequest
+	ShippingAddress *models.OrderAddress
+	BillingAddress  *models.OrderAddress
+	// Currency is the currency the order is priced in. Empty uses the
+	// currency every product is priced in.
+	Currency string
+}
+
+// mergeItems adds up the quantities of items naming the same product and
+// orders them by product ID, so that concurrent orders lock product rows in
+// the same order and cannot deadlock.
+func mergeItems(items []OrderItem) []OrderItem {
+	merged := make([]OrderItem, 0, len(items))
+	index := make(map[int64]int, len(items))
+	for _, item := range items {
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].ProductID < merged[j].ProductID })
+	return merged
+}
+
+// orderCurrency returns the currency of a new order for products: the one
+// asked for, or else the one all products are priced in. Every product must
+// have a price in it.
+func orderCurrency(requested string, products []models.Product) (string, error) {
+	currency := requested
+	if currency == "" && len(products) > 0 {
+		currency = products[0].Currency
+		for _, product := range products {
+			if product.Currency != currency {
+				return "", ErrMixedCurrencies
+			}
+		}
+	}
+	for _, product := range products {
+		if _, ok := product.PriceIn(currency); !ok {
+			return "", fmt.Errorf("%w: %d", ErrNoPriceInCurrency, product.ID)
+		}
+	}
+	return currency, nil
+}
+
+// CreateOrder reserves stock for every item and records the order in a single
+// transaction. Each product row is locked while its stock policy is applied:
+// rejecting products fail with ErrInsufficientStock, backorder products take
+// what is available and backorder the rest, and pre-order products backorder
+// the whole quantity. Units taken from stock are allocated to warehouses with
+// the requested allocation rule, and items naming the same product are merged
+// into one line priced at the product's current price in the order currency. It
+// returns the order as stored and the products as they stand afterwards.
+func (s *SQL) CreateOrder(order NewOrder) (models.Order, []models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Order{}, nil, err
+	}
+	defer tx.Rollback()
+
+	var customerID sql.NullInt64
+	if order.CustomerID != 0 {
+		var exists int
+		err := tx.QueryRow("SELECT 1 FROM customers WHERE id = ?", order.CustomerID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return models.Order{}, nil, ErrCustomerNotFound
+		}
+		if err != nil {
+			return models.Order{}, nil, err
+		}
+		customerID = sql.NullInt64{Int64: order.CustomerID, Valid: true}
+	}
+
+	// Every product is locked before the order is written, since its
+	// currency depends on all of them.
+	items := mergeItems(order.Items)
+	products := make([]models.Product, len(items))
+	for i, item := range items {
+		products[i], err = lockProduct(tx, item.ProductID)
+		if err != nil {
+			return models.Order{}, nil, err
+		}
+	}
+	currency, err := orderCurrency(order.Currency, products)
 	if err != nil {
-		return 0, err
+		return models.Order{}, nil, err
 	}
 
-	orderID, err := result.LastInsertId()
+	// Timestamps come from the application rather than NOW(), which not every
+	// dialect has.
+	now := nowUTC()
+
+	// Simpan order ke dalam tabel orders
+	orderID, err := tx.Insert("INSERT INTO orders (customer_id, currency, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?)", customerID, currency, models.OrderStatusPending, now, now)
 	if err != nil {
-		return 0, err
+		return models.Order{}, nil, err
 	}
 
-	// Simpan produk terkait order di tabel order_products
-	for _, product := range products {
-		_, err := db.Exec("INSERT INTO order_products (order_id, product_id, quantity, created_at, updated_at) VALUES (?, ?, ?, NOW(), NOW())",
-			orderID, product.ID, product.Quantity)
+	if err := saveOrderAddress(tx, orderID, addressShipping, order.ShippingAddress); err != nil {
+		return models.Order{}, nil, err
+	}
+	if err := saveOrderAddress(tx, orderID, addressBilling, order.BillingAddress); err != nil {
+		return models.Order{}, nil, err
+	}
+
+	var lines []models.OrderLine
+	var updated []models.Product
+	for i, item := range items {
+		product := products[i]
+		unitPrice, _ := product.PriceIn(currency)
+
+		allocated, backordered, err := reserve(product, item.Quantity)
+		if err != nil {
+			return models.Order{}, nil, fmt.Errorf("%w: %d", err, item.ProductID)
+		}
+
+		_, err = tx.Exec("UPDATE products SET stock = stock - ?, sold = sold + ?, updated_at = ? WHERE id = ?",
+			allocated, item.Quantity, now, product.ID)
+		if err != nil {
+			return models.Order{}, nil, err
+		}
+		product.Stock -= allocated
+		product.Sold += item.Quantity
+		product.UpdatedAt = now
+
+		// Simpan produk terkait order di tabel order_products
+		_, err = tx.Exec("INSERT INTO order_products (order_id, product_id, product_name, quantity, backordered_quantity, unit_price, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			orderID, product.ID, product.Name, item.Quantity, backordered, unitPrice, now, now)
 		if err != nil {
-			return 0, err
+			return models.Order{}, nil, err
 		}
+
+		var allocations []models.Allocation
+		if allocated > 0 {
+			allocations, err = allocateWarehouseStock(tx, orderID, product.ID, allocated, order.Allocation, now)
+			if err != nil {
+				return models.Order{}, nil, err
+			}
+		}
+
+		lines = append(lines, models.NewOrderLine(product, item.Quantity, unitPrice, backordered, allocations))
+		updated = append(updated, product)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Order{}, nil, err
+	}
+
+	stored := models.Order{
+		ID:              &orderID,
+		CustomerID:      nullInt64Ptr(customerID),
+		Currency:        currency,
+		Status:          models.OrderStatusPending,
+		CreatedAt:       &now,
+		UpdatedAt:       &now,
+		Lines:           lines,
+		ShippingAddress: order.ShippingAddress,
+		BillingAddress:  order.BillingAddress,
+	}
+	return stored, updated, nil
+}
+
+// lockProduct reads a product and locks its row for the rest of the transaction.
+func lockProduct(tx *transaction, id int64) (models.Product, error) {
+	var product models.Product
+	err := tx.QueryRow("SELECT id, name, price, currency, sold, stock, reorder_threshold, stock_policy, created_at, updated_at FROM products WHERE id = ? FOR UPDATE", id).Scan(
+		&product.ID, &product.Name, &product.Price, &product.Currency, &product.Sold, &product.Stock, &product.ReorderThreshold, &product.StockPolicy, scanTime(&product.CreatedAt), scanTime(&product.UpdatedAt),
+	)
+	if err == sql.ErrNoRows {
+		return models.Product{}, fmt.Errorf("%w: %d", ErrProductNotFound, id)
+	}
+	if err != nil {
+		return models.Product{}, err
 	}
 
-	return orderID, nil
+	prices, err := getProductPrices(tx, "WHERE product_id = ?", id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	product.Prices = prices[id]
+	return product, nil
 }
 
-func GetOrderByID(db *sql.DB, id int64) (models.Order, error) {
+// reserve splits a requested quantity into the units taken from stock now and
+// the units backordered, according to the product's stock policy.
+func reserve(product models.Product, quantity int64) (allocated int64, backordered int64, err error) {
+	switch product.StockPolicy {
+	case models.StockPolicyPreorder:
+		return 0, quantity, nil
+	case models.StockPolicyBackorder:
+		if product.Stock >= quantity {
+			return quantity, 0, nil
+		}
+		if product.Stock < 0 {
+			return 0, quantity, nil
+		}
+		return product.Stock, quantity - product.Stock, nil
+	default:
+		if product.Stock < quantity {
+			return 0, 0, ErrInsufficientStock
+		}
+		return quantity, 0, nil
+	}
+}
+
+func (s *SQL) GetOrderByID(id int64) (models.Order, error) {
 	var order models.Order
 
-	query := `SELECT id, created_at, updated_at FROM orders WHERE id = ?`
-	err := db.QueryRow(query, id).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	var customerID sql.NullInt64
+	query := `SELECT id, customer_id, currency, status, created_at, updated_at FROM orders WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&order.ID, &customerID, &order.Currency, &order.Status, scanNullTime(&order.CreatedAt), scanNullTime(&order.UpdatedAt))
+	if err == sql.ErrNoRows {
+		return models.Order{}, nil
+	}
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.CustomerID = nullInt64Ptr(customerID)
+
+	addresses, err := getOrderAddresses(s.db, "WHERE order_id = ?", id)
 	if err != nil {
 		return models.Order{}, err
 	}
+	order.ShippingAddress = addresses[id][addressShipping]
+	order.BillingAddress = addresses[id][addressBilling]
 
-	// Dapatkan produk terkait
-	order.Products, err = GetProductsByOrderID(db, id)
+	order.Lines, err = getOrderLines(s.db, id)
 	if err != nil {
 		return models.Order{}, err
 	}
@@ -99,13 +376,15 @@ func GetOrderByID(db *sql.DB, id int64) (models.Order, error) {
 	return order, nil
 }
 
-// Fungsi untuk mendapatkan produk berdasarkan order ID
-func GetProductsByOrderID(db *sql.DB, orderID int64) ([]models.Product, error) {
-	var products []models.Product
-	query := `SELECT p.id, p.name, p.price, op.quantity, p.stock, p.sold, p.created_at, p.updated_at
+// getOrderLines returns the lines of an order in product ID order.
+func getOrderLines(db *database, orderID int64) ([]models.OrderLine, error) {
+	var lines []models.OrderLine
+	query := `SELECT op.quantity, op.backordered_quantity, op.unit_price, ` + orderLineProductColumns + `
 			  FROM order_products op
-			  JOIN products p ON op.product_id = p.id
-			  WHERE op.order_id = ?`
+			  JOIN orders o ON op.order_id = o.id
+			  LEFT JOIN products p ON op.product_id = p.id
+			  WHERE op.order_id = ?
+			  ORDER BY op.product_id`
 
 	rows, err := db.Query(query, orderID)
 	if err != nil {
@@ -114,18 +393,223 @@ func GetProductsByOrderID(db *sql.DB, orderID int64) ([]models.Product, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var product models.Product
-		if err := rows.Scan(&product.ID, &product.Name, &product.Price, &product.Quantity, &product.Stock, &product.Sold, &product.CreatedAt, &product.UpdatedAt); err != nil {
+		var line models.OrderLine
+		if err := rows.Scan(orderLineDest(&line)...); err != nil {
+			return nil, err
+		}
+		line.LineTotal = line.Quantity * line.UnitPrice
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	allocations, err := getOrderAllocations(db, "WHERE order_id = ?", orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lines {
+		lines[i].Allocations = allocations[[2]int64{orderID, lines[i].Product.ID}]
+	}
+
+	return lines, nil
+}
+
+// GetSalesByCurrency totals the order lines of every order by order currency.
+func (s *SQL) GetSalesByCurrency() ([]models.SalesTotal, error) {
+	rows, err := s.db.Query(`
+		SELECT o.currency, COUNT(DISTINCT o.id), COALESCE(SUM(op.quantity * op.unit_price), 0)
+		FROM orders o
+		JOIN order_products op ON o.id = op.order_id
+		GROUP BY o.currency
+		ORDER BY o.currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []models.SalesTotal{}
+	for rows.Next() {
+		var total models.SalesTotal
+		if err := rows.Scan(&total.Currency, &total.Orders, &total.Total); err != nil {
 			return nil, err
 		}
-		products = append(products, product)
+		totals = append(totals, total)
 	}
 
-	return products, nil
+	return totals, rows.Err()
 }
 
-func DeleteOrderByID(db *sql.DB, id int64) error {
+func (s *SQL) DeleteOrderByID(id int64) error {
 	query := `DELETE FROM orders WHERE id = ?`
-	_, err := db.Exec(query, id)
+	_, err := s.db.Exec(query, id)
 	return err
 }
+
+// UpdateOrderItems replaces the line items of a pending order. Items list the
+// desired quantity of each product; products left out or given a quantity of
+// zero are removed. Stock moves by the difference from the current lines in a
+// single transaction: increases are reserved like a new order, and decreases
+// cancel backordered units first before returning the rest to stock and to
+// the warehouses they were allocated from. Lines of deleted products can only
+// be reduced or removed. It returns the remaining products as they stand
+// after the change.
+func (s *SQL) UpdateOrderItems(orderID int64, items []OrderItem, allocation models.AllocationRequest) ([]models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status, currency string
+	err = tx.QueryRow("SELECT status, currency FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status, &currency)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != models.OrderStatusPending {
+		return nil, ErrOrderNotPending
+	}
+
+	type line struct {
+		quantity    int64
+		backordered int64
+	}
+	current := make(map[int64]line)
+	rows, err := tx.Query("SELECT product_id, quantity, backordered_quantity FROM order_products WHERE order_id = ?", orderID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var productID int64
+		var l line
+		if err := rows.Scan(&productID, &l.quantity, &l.backordered); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		current[productID] = l
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	desired := make(map[int64]int64)
+	for _, item := range items {
+		desired[item.ProductID] += item.Quantity
+	}
+
+	// Lock products in ID order so concurrent edits cannot deadlock.
+	var productIDs []int64
+	for id := range current {
+		productIDs = append(productIDs, id)
+	}
+	for id := range desired {
+		if _, ok := current[id]; !ok {
+			productIDs = append(productIDs, id)
+		}
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	now := nowUTC()
+	var updated []models.Product
+	for _, productID := range productIDs {
+		old := current[productID]
+		quantity := desired[productID]
+		delta := quantity - old.quantity
+		if delta == 0 {
+			continue
+		}
+
+		// A line of a deleted product can still be reduced or dropped; there
+		// is no stock left to return it to.
+		product, err := lockProduct(tx, productID)
+		deleted := errors.Is(err, ErrProductNotFound) && delta < 0
+		if err != nil && !deleted {
+			return nil, err
+		}
+
+		// New lines are priced in the order currency; existing lines keep
+		// the price they were ordered at.
+		unitPrice, ok := product.PriceIn(currency)
+		if old.quantity == 0 && !ok {
+			return nil, fmt.Errorf("%w: %d", ErrNoPriceInCurrency, productID)
+		}
+
+		backordered := old.backordered
+		if delta > 0 {
+			allocated, short, err := reserve(product, delta)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %d", err, productID)
+			}
+
+			_, err = tx.Exec("UPDATE products SET stock = stock - ?, sold = sold + ?, updated_at = ? WHERE id = ?",
+				allocated, delta, now, productID)
+			if err != nil {
+				return nil, err
+			}
+			product.Stock -= allocated
+
+			if allocated > 0 {
+				if _, err := allocateWarehouseStock(tx, orderID, productID, allocated, allocation, now); err != nil {
+					return nil, err
+				}
+			}
+			backordered += short
+		} else {
+			released := -delta
+			cancelled := released
+			if cancelled > backordered {
+				cancelled = backordered
+			}
+			returned := released - cancelled
+			backordered -= cancelled
+
+			if !deleted {
+				_, err = tx.Exec("UPDATE products SET low_stock_alerted = CASE WHEN stock + ? >= reorder_threshold THEN 0 ELSE low_stock_alerted END, stock = stock + ?, sold = sold - ?, updated_at = ? WHERE id = ?",
+					returned, returned, released, now, productID)
+				if err != nil {
+					return nil, err
+				}
+				product.Stock += returned
+			}
+
+			if err := releaseWarehouseAllocations(tx, orderID, productID, returned); err != nil {
+				return nil, err
+			}
+		}
+		product.Sold += delta
+		product.UpdatedAt = now
+
+		switch {
+		case quantity == 0:
+			_, err = tx.Exec("DELETE FROM order_products WHERE order_id = ? AND product_id = ?", orderID, productID)
+		case old.quantity == 0:
+			_, err = tx.Exec("INSERT INTO order_products (order_id, product_id, product_name, quantity, backordered_quantity, unit_price, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+				orderID, productID, product.Name, quantity, backordered, unitPrice, now, now)
+		default:
+			_, err = tx.Exec("UPDATE order_products SET quantity = ?, backordered_quantity = ?, updated_at = ? WHERE order_id = ? AND product_id = ?",
+				quantity, backordered, now, orderID, productID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !deleted {
+			updated = append(updated, product)
+		}
+	}
+
+	_, err = tx.Exec("UPDATE orders SET updated_at = ? WHERE id = ?", now, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
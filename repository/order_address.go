@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"database/sql"
+)
+
+// Address types stored in order_addresses.
+const (
+	addressShipping = "shipping"
+	addressBilling  = "billing"
+)
+
+// saveOrderAddress stores or replaces the snapshot of one address on an order.
+func saveOrderAddress(tx *transaction, orderID int64, addressType string, address *models.OrderAddress) error {
+	if address == nil {
+		return nil
+	}
+
+	_, err := tx.Exec("DELETE FROM order_addresses WHERE order_id = ? AND type = ?", orderID, addressType)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO order_addresses (order_id, type, name, phone, line1, line2, city, province, postal_code, country) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		orderID, addressType, address.Name, address.Phone, address.Line1, address.Line2, address.City, address.Province, address.PostalCode, address.Country)
+	return err
+}
+
+// getOrderAddresses returns the address snapshots of orders keyed by order ID
+// and address type.
+func getOrderAddresses(q queryer, where string, args ...interface{}) (map[int64]map[string]*models.OrderAddress, error) {
+	rows, err := q.Query("SELECT order_id, type, name, phone, line1, line2, city, province, postal_code, country FROM order_addresses "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make(map[int64]map[string]*models.OrderAddress)
+	for rows.Next() {
+		var orderID int64
+		var addressType string
+		var address models.OrderAddress
+		err := rows.Scan(&orderID, &addressType, &address.Name, &address.Phone, &address.Line1, &address.Line2, &address.City, &address.Province, &address.PostalCode, &address.Country)
+		if err != nil {
+			return nil, err
+		}
+		if addresses[orderID] == nil {
+			addresses[orderID] = make(map[string]*models.OrderAddress)
+		}
+		addresses[orderID][addressType] = &address
+	}
+
+	return addresses, rows.Err()
+}
+
+// UpdateOrderAddresses replaces the shipping and/or billing address of a
+// pending order. A nil address is left unchanged.
+func (s *SQL) UpdateOrderAddresses(orderID int64, shipping, billing *models.OrderAddress) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.OrderStatusPending {
+		return ErrOrderNotPending
+	}
+
+	if err := saveOrderAddress(tx, orderID, addressShipping, shipping); err != nil {
+		return err
+	}
+	if err := saveOrderAddress(tx, orderID, addressBilling, billing); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE orders SET updated_at = ? WHERE id = ?", nowUTC(), orderID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
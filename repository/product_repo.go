@@ -3,94 +3,264 @@ package repository
 import (
 	"api-productnorder/models"
 	"database/sql"
-	"time"
 )
 
-func GetAllProducts(db *sql.DB) ([]models.Datum, error) {
-	rows, err := db.Query("SELECT id, name, price, sold, stock, created_at, updated_at FROM products")
+func (s *SQL) GetAllProducts() ([]models.Product, error) {
+	rows, err := s.db.Query("SELECT id, name, price, currency, sold, stock, reorder_threshold, stock_policy, created_at, updated_at FROM products")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var products []models.Datum
+	var products []models.Product
 	for rows.Next() {
-		var product models.Datum
-		err := rows.Scan(&product.ID, &product.Name, &product.Price, &product.Sold, &product.Stock, &product.CreatedAt, &product.UpdatedAt)
+		var product models.Product
+		err := rows.Scan(&product.ID, &product.Name, &product.Price, &product.Currency, &product.Sold, &product.Stock, &product.ReorderThreshold, &product.StockPolicy, scanTime(&product.CreatedAt), scanTime(&product.UpdatedAt))
 		if err != nil {
 			return nil, err
 		}
 		products = append(products, product)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prices, err := getProductPrices(s.db, "")
+	if err != nil {
+		return nil, err
+	}
+	stocks, err := s.GetAllWarehouseStock()
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Prices = prices[products[i].ID]
+		products[i].Warehouses = stocks[products[i].ID]
+	}
+
+	return products, nil
+}
+
+// GetLowStockProducts returns products whose stock has fallen below their
+// reorder threshold, lowest stock first.
+func (s *SQL) GetLowStockProducts() ([]models.Product, error) {
+	rows, err := s.db.Query("SELECT id, name, price, currency, sold, stock, reorder_threshold, stock_policy, created_at, updated_at FROM products WHERE stock < reorder_threshold ORDER BY stock, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var product models.Product
+		err := rows.Scan(&product.ID, &product.Name, &product.Price, &product.Currency, &product.Sold, &product.Stock, &product.ReorderThreshold, &product.StockPolicy, scanTime(&product.CreatedAt), scanTime(&product.UpdatedAt))
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prices, err := getProductPrices(s.db, "WHERE product_id IN (SELECT id FROM products WHERE stock < reorder_threshold)")
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Prices = prices[products[i].ID]
+	}
+
 	return products, nil
 }
 
-func CreateProduct(db *sql.DB, name string, price int64, stock int64) (models.Data, error) {
-	createdAt := time.Now().Format("2006-01-02 15:04:05")
+// CreateProduct stores a product together with its price list.
+func (s *SQL) CreateProduct(name string, price int64, currency string, prices []models.ProductPrice, stock int64, reorderThreshold int64, stockPolicy string) (models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Product{}, err
+	}
+	defer tx.Rollback()
+
+	createdAt := nowUTC()
 	updatedAt := createdAt
 	sold := int64(0)
 
-	result, err := db.Exec("INSERT INTO products (name, price, stock, sold, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)", name, price, stock, sold, createdAt, updatedAt)
+	id, err := tx.Insert("INSERT INTO products (name, price, currency, stock, sold, reorder_threshold, stock_policy, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		name, price, currency, stock, sold, reorderThreshold, stockPolicy, createdAt, updatedAt)
 	if err != nil {
-		return models.Data{}, err
+		return models.Product{}, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return models.Data{}, err
+	if err := insertProductPrices(tx, id, prices); err != nil {
+		return models.Product{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, err
 	}
 
-	product := models.Data{
-		ID:        id,
-		Name:      name,
-		Price:     price,
-		Stock:     stock,
-		Sold:      sold,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
+	product := models.Product{
+		ID:               id,
+		Name:             name,
+		Price:            price,
+		Currency:         currency,
+		Prices:           prices,
+		Stock:            stock,
+		Sold:             sold,
+		ReorderThreshold: reorderThreshold,
+		StockPolicy:      stockPolicy,
+		CreatedAt:        createdAt,
+		UpdatedAt:        updatedAt,
 	}
 
 	return product, nil
 }
 
-func GetProductByID(db *sql.DB, id int64) (models.Data, error) {
-	var product models.Data
-	err := db.QueryRow("SELECT id, name, price, sold, stock, created_at, updated_at FROM products WHERE id = ?", id).Scan(
-		&product.ID, &product.Name, &product.Price, &product.Sold, &product.Stock, &product.CreatedAt, &product.UpdatedAt,
+func (s *SQL) GetProductByID(id int64) (models.Product, error) {
+	var product models.Product
+	err := s.db.QueryRow("SELECT id, name, price, currency, sold, stock, reorder_threshold, stock_policy, created_at, updated_at FROM products WHERE id = ?", id).Scan(
+		&product.ID, &product.Name, &product.Price, &product.Currency, &product.Sold, &product.Stock, &product.ReorderThreshold, &product.StockPolicy, scanTime(&product.CreatedAt), scanTime(&product.UpdatedAt),
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Data{}, nil
+			return models.Product{}, nil
 		}
-		return models.Data{}, err
+		return models.Product{}, err
+	}
+
+	prices, err := getProductPrices(s.db, "WHERE product_id = ?", id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	product.Prices = prices[id]
+
+	product.Warehouses, err = s.GetWarehouseStock(id)
+	if err != nil {
+		return models.Product{}, err
 	}
 	return product, nil
 }
 
-func UpdateProduct(db *sql.DB, id int64, name string, price int64, stock int64) (models.Data, error) {
-	updatedAt := time.Now().Format("2006-01-02 15:04:05")
-	_, err := db.Exec("UPDATE products SET name = ?, price = ?, stock = ?, updated_at = ? WHERE id = ?", name, price, stock, updatedAt, id)
+// UpdateProduct overwrites a product and replaces its price list. A pending
+// low-stock alert is cleared once stock is back at or above the reorder
+// threshold.
+func (s *SQL) UpdateProduct(id int64, name string, price int64, currency string, prices []models.ProductPrice, stock int64, reorderThreshold int64, stockPolicy string) (models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Product{}, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT 1 FROM products WHERE id = ? FOR UPDATE", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return models.Product{}, nil
+	}
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	// Warehouse stock only changes with the product row locked, so the total
+	// cannot move before the update commits.
+	var assigned int64
+	err = tx.QueryRow("SELECT COALESCE(SUM(stock), 0) FROM warehouse_stock WHERE product_id = ?", id).Scan(&assigned)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if stock < assigned {
+		return models.Product{}, ErrStockBelowWarehouses
+	}
+
+	updatedAt := nowUTC()
+	// The comparison is made here rather than in SQL, where PostgreSQL would
+	// compare two untyped parameters as text.
+	restocked := stock >= reorderThreshold
+	_, err = tx.Exec("UPDATE products SET low_stock_alerted = CASE WHEN ? THEN 0 ELSE low_stock_alerted END, name = ?, price = ?, currency = ?, stock = ?, reorder_threshold = ?, stock_policy = ?, updated_at = ? WHERE id = ?",
+		restocked, name, price, currency, stock, reorderThreshold, stockPolicy, updatedAt, id)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	_, err = tx.Exec("DELETE FROM product_prices WHERE product_id = ?", id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if err := insertProductPrices(tx, id, prices); err != nil {
+		return models.Product{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, err
+	}
+
+	return s.GetProductByID(id)
+}
+
+func insertProductPrices(tx *transaction, productID int64, prices []models.ProductPrice) error {
+	for _, price := range prices {
+		_, err := tx.Exec("INSERT INTO product_prices (product_id, currency, price) VALUES (?, ?, ?)", productID, price.Currency, price.Price)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getProductPrices returns price lists keyed by product ID, ordered by
+// currency.
+func getProductPrices(q queryer, where string, args ...interface{}) (map[int64][]models.ProductPrice, error) {
+	rows, err := q.Query("SELECT product_id, currency, price FROM product_prices "+where+" ORDER BY product_id, currency", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[int64][]models.ProductPrice)
+	for rows.Next() {
+		var productID int64
+		var price models.ProductPrice
+		if err := rows.Scan(&productID, &price.Currency, &price.Price); err != nil {
+			return nil, err
+		}
+		prices[productID] = append(prices[productID], price)
+	}
+
+	return prices, rows.Err()
+}
+
+// MarkLowStockAlerted flags a product as alerted if it is below its reorder
+// threshold and has not been alerted since it was last restocked. It reports
+// whether the flag was set by this call, so concurrent orders emit at most
+// one alert per crossing.
+func (s *SQL) MarkLowStockAlerted(id int64) (bool, error) {
+	result, err := s.db.Exec("UPDATE products SET low_stock_alerted = 1 WHERE id = ? AND low_stock_alerted = 0 AND stock < reorder_threshold", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return models.Data{}, err
+		return false, err
 	}
 
-	return GetProductByID(db, id)
+	return affected == 1, nil
 }
 
-func DeleteProduct(db *sql.DB, id int64) (models.Data, error) {
-	product, err := GetProductByID(db, id)
+func (s *SQL) DeleteProduct(id int64) (models.Product, error) {
+	product, err := s.GetProductByID(id)
 	if err != nil {
-		return models.Data{}, err
+		return models.Product{}, err
 	}
 
 	if product.ID == 0 {
-		return models.Data{}, nil
+		return models.Product{}, nil
 	}
 
-	_, err = db.Exec("DELETE FROM products WHERE id = ?", id)
+	_, err = s.db.Exec("DELETE FROM products WHERE id = ?", id)
 	if err != nil {
-		return models.Data{}, err
+		return models.Product{}, err
 	}
 
 	return product, nil
@@ -0,0 +1,115 @@
+// Package repository stores the API's data. Each kind of record has a
+// repository interface; SQL keeps them in a database and Memory keeps them
+// in process memory with the same semantics.
+package repository
+
+import (
+	"api-productnorder/models"
+	"database/sql"
+)
+
+// ProductRepository stores products and their stock.
+type ProductRepository interface {
+	GetAllProducts() ([]models.Product, error)
+	// GetLowStockProducts returns products whose stock has fallen below
+	// their reorder threshold, lowest stock first.
+	GetLowStockProducts() ([]models.Product, error)
+	// CreateProduct stores a product priced at price in currency, with
+	// optional fixed prices in other currencies.
+	CreateProduct(name string, price int64, currency string, prices []models.ProductPrice, stock int64, reorderThreshold int64, stockPolicy string) (models.Product, error)
+	// GetProductByID returns a zero product if there is none with the ID.
+	GetProductByID(id int64) (models.Product, error)
+	// UpdateProduct returns a zero product if there is none with the ID, and
+	// ErrStockBelowWarehouses if stock is less than its warehouses hold.
+	UpdateProduct(id int64, name string, price int64, currency string, prices []models.ProductPrice, stock int64, reorderThreshold int64, stockPolicy string) (models.Product, error)
+	MarkLowStockAlerted(id int64) (bool, error)
+	// DeleteProduct returns a zero product if there is none with the ID.
+	DeleteProduct(id int64) (models.Product, error)
+	AdjustStock(productID int64, warehouseID int64, delta int64, reason string) (models.StockAdjustment, models.Product, error)
+}
+
+// OrderRepository stores orders, their lines and their addresses.
+type OrderRepository interface {
+	// GetOrders returns every order, ordered by ID.
+	GetOrders() ([]models.Order, error)
+	GetOrdersByCustomer(customerID int64) ([]models.Order, error)
+	CreateOrder(order NewOrder) (models.Order, []models.Product, error)
+	// GetOrderByID returns an order without an ID if there is none with the ID.
+	GetOrderByID(id int64) (models.Order, error)
+	DeleteOrderByID(id int64) error
+	UpdateOrderItems(orderID int64, items []OrderItem, allocation models.AllocationRequest) ([]models.Product, error)
+	UpdateOrderAddresses(orderID int64, shipping, billing *models.OrderAddress) error
+	// GetSalesByCurrency totals the order lines of every order by order
+	// currency, ordered by currency. Converted is left zero.
+	GetSalesByCurrency() ([]models.SalesTotal, error)
+}
+
+// ExchangeRateRepository stores the exchange rates reports convert with.
+type ExchangeRateRepository interface {
+	// GetExchangeRates returns every rate, ordered by currency.
+	GetExchangeRates() ([]models.ExchangeRate, error)
+	// SetExchangeRate adds or replaces the rate of a currency.
+	SetExchangeRate(currency string, rate float64) (models.ExchangeRate, error)
+}
+
+// WarehouseRepository stores warehouses.
+type WarehouseRepository interface {
+	// GetAllWarehouses returns every warehouse in priority order.
+	GetAllWarehouses() ([]models.Warehouse, error)
+	CreateWarehouse(warehouse models.Warehouse) (models.Warehouse, error)
+	// GetWarehouseByID returns a zero warehouse if there is none with the ID.
+	GetWarehouseByID(id int64) (models.Warehouse, error)
+	// UpdateWarehouse returns a zero warehouse if there is none with the ID.
+	UpdateWarehouse(id int64, warehouse models.Warehouse) (models.Warehouse, error)
+	// DeleteWarehouse returns a zero warehouse if there is none with the ID.
+	DeleteWarehouse(id int64) (models.Warehouse, error)
+}
+
+// CustomerRepository stores customers and their address books.
+type CustomerRepository interface {
+	// GetAllCustomers returns every customer, ordered by ID.
+	GetAllCustomers() ([]models.Customer, error)
+	CreateCustomer(customer models.Customer) (models.Customer, error)
+	// GetCustomerByID returns a zero customer if there is none with the ID.
+	GetCustomerByID(id int64) (models.Customer, error)
+	// UpdateCustomer returns a zero customer if there is none with the ID.
+	UpdateCustomer(id int64, customer models.Customer) (models.Customer, error)
+	// DeleteCustomer returns a zero customer if there is none with the ID.
+	DeleteCustomer(id int64) (models.Customer, error)
+}
+
+// APIKeyRepository stores API keys by their hash.
+type APIKeyRepository interface {
+	// GetAllAPIKeys returns every key, ordered by ID.
+	GetAllAPIKeys() ([]models.APIKey, error)
+	CreateAPIKey(name, prefix, hash string, scopes []string) (models.APIKey, error)
+	GetActiveAPIKeyByHash(hash string) (models.APIKey, error)
+	// RevokeAPIKey returns a zero key if there is none with the ID.
+	RevokeAPIKey(id int64) (models.APIKey, error)
+	TouchAPIKey(id int64) error
+}
+
+// Store holds every repository the API needs.
+type Store interface {
+	ProductRepository
+	OrderRepository
+	WarehouseRepository
+	CustomerRepository
+	APIKeyRepository
+	ExchangeRateRepository
+}
+
+// SQL implements Store on a relational database.
+type SQL struct {
+	db *database
+}
+
+// NewSQL returns a Store backed by db, which speaks dialect.
+func NewSQL(db *sql.DB, dialect Dialect) *SQL {
+	return &SQL{db: newDatabase(db, dialect)}
+}
+
+var (
+	_ Store = (*SQL)(nil)
+	_ Store = (*Memory)(nil)
+)
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"database/sql"
+	"time"
+)
+
+// AdjustStock applies a relative stock change to a product in a single
+// statement so it cannot race with orders decrementing the same row. When
+// warehouseID is non-zero the change is also applied to that warehouse's
+// stock; otherwise it applies to stock not assigned to any warehouse. The
+// change is rejected with ErrInsufficientStock if it would make stock negative.
+// Incoming stock is allocated to outstanding backorders oldest order first,
+// and stock left at or above the reorder threshold re-arms the low-stock alert.
+func (s *SQL) AdjustStock(productID int64, warehouseID int64, delta int64, reason string) (models.StockAdjustment, models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.StockAdjustment{}, models.Product{}, err
+	}
+	defer tx.Rollback()
+
+	now := nowUTC()
+
+	// Removing unassigned stock must not eat into stock held by warehouses.
+	query := "UPDATE products SET stock = stock + ?, updated_at = ? WHERE id = ? AND stock + ? >= 0"
+	args := []interface{}{delta, now, productID, delta}
+	if warehouseID == 0 && delta < 0 {
+		query += " AND stock + ? >= (SELECT COALESCE(SUM(stock), 0) FROM warehouse_stock WHERE product_id = ?)"
+		args = append(args, delta, productID)
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return models.StockAdjustment{}, models.Product{}, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return models.StockAdjustment{}, models.Product{}, err
+	}
+
+	if affected == 0 {
+		var exists int
+		err := tx.QueryRow("SELECT 1 FROM products WHERE id = ?", productID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return models.StockAdjustment{}, models.Product{}, ErrProductNotFound
+		}
+		if err != nil {
+			return models.StockAdjustment{}, models.Product{}, err
+		}
+		return models.StockAdjustment{}, models.Product{}, ErrInsufficientStock
+	}
+
+	var warehouse sql.NullInt64
+	if warehouseID != 0 {
+		if err := adjustWarehouseStock(tx, productID, warehouseID, delta); err != nil {
+			return models.StockAdjustment{}, models.Product{}, err
+		}
+		warehouse = sql.NullInt64{Int64: warehouseID, Valid: true}
+	}
+
+	id, err := tx.Insert("INSERT INTO stock_adjustments (product_id, warehouse_id, delta, reason, created_at) VALUES (?, ?, ?, ?, ?)",
+		productID, warehouse, delta, reason, now)
+	if err != nil {
+		return models.StockAdjustment{}, models.Product{}, err
+	}
+
+	var allocated int64
+	if delta > 0 {
+		allocated, err = allocateBackorders(tx, productID, warehouseID, now)
+		if err != nil {
+			return models.StockAdjustment{}, models.Product{}, err
+		}
+	}
+
+	// The alert is re-armed on the stock left once backorders have taken
+	// their share, so a restock they consume entirely does not count.
+	_, err = tx.Exec("UPDATE products SET low_stock_alerted = 0 WHERE id = ? AND stock >= reorder_threshold", productID)
+	if err != nil {
+		return models.StockAdjustment{}, models.Product{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.StockAdjustment{}, models.Product{}, err
+	}
+
+	adjustment := models.StockAdjustment{
+		ID:                  id,
+		ProductID:           productID,
+		WarehouseID:         warehouseID,
+		Delta:               delta,
+		Reason:              reason,
+		CreatedAt:           now,
+		BackordersAllocated: allocated,
+	}
+
+	product, err := s.GetProductByID(productID)
+	if err != nil {
+		return models.StockAdjustment{}, models.Product{}, err
+	}
+
+	return adjustment, product, nil
+}
+
+// adjustWarehouseStock applies delta to a product's stock at one warehouse,
+// creating the stock row on first restock.
+func adjustWarehouseStock(tx *transaction, productID, warehouseID, delta int64) error {
+	var exists int
+	err := tx.QueryRow("SELECT 1 FROM warehouses WHERE id = ?", warehouseID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrWarehouseNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE warehouse_stock SET stock = stock + ? WHERE warehouse_id = ? AND product_id = ? AND stock + ? >= 0",
+		delta, warehouseID, productID, delta)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	err = tx.QueryRow("SELECT 1 FROM warehouse_stock WHERE warehouse_id = ? AND product_id = ?", warehouseID, productID).Scan(&exists)
+	if err == nil || delta < 0 {
+		return ErrInsufficientStock
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO warehouse_stock (warehouse_id, product_id, stock) VALUES (?, ?, ?)", warehouseID, productID, delta)
+	return err
+}
+
+// allocateBackorders fills backordered order lines for a product, oldest
+// order first, and returns the number of units allocated. Units come from the
+// given warehouse, or from unassigned stock when warehouseID is zero. The
+// product row must already be locked by the caller's transaction.
+func allocateBackorders(tx *transaction, productID int64, warehouseID int64, now time.Time) (int64, error) {
+	var available int64
+	var err error
+	if warehouseID != 0 {
+		err = tx.QueryRow("SELECT stock FROM warehouse_stock WHERE warehouse_id = ? AND product_id = ?", warehouseID, productID).Scan(&available)
+	} else {
+		err = tx.QueryRow("SELECT p.stock - COALESCE(SUM(ws.stock), 0) FROM products p LEFT JOIN warehouse_stock ws ON ws.product_id = p.id WHERE p.id = ? GROUP BY p.id, p.stock", productID).Scan(&available)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if available <= 0 {
+		return 0, nil
+	}
+
+	rows, err := tx.Query("SELECT order_id, backordered_quantity FROM order_products WHERE product_id = ? AND backordered_quantity > 0 ORDER BY order_id", productID)
+	if err != nil {
+		return 0, err
+	}
+
+	type backorder struct {
+		orderID  int64
+		quantity int64
+	}
+	var backorders []backorder
+	for rows.Next() {
+		var b backorder
+		if err := rows.Scan(&b.orderID, &b.quantity); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		backorders = append(backorders, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var allocated int64
+	for _, b := range backorders {
+		if available == 0 {
+			break
+		}
+
+		n := b.quantity
+		if n > available {
+			n = available
+		}
+
+		_, err := tx.Exec("UPDATE order_products SET backordered_quantity = backordered_quantity - ?, updated_at = ? WHERE order_id = ? AND product_id = ?",
+			n, now, b.orderID, productID)
+		if err != nil {
+			return 0, err
+		}
+
+		if warehouseID != 0 {
+			allocation := models.Allocation{WarehouseID: warehouseID, Quantity: n}
+			if err := recordAllocation(tx, b.orderID, productID, allocation, now); err != nil {
+				return 0, err
+			}
+		}
+
+		available -= n
+		allocated += n
+	}
+
+	if allocated > 0 {
+		_, err := tx.Exec("UPDATE products SET stock = stock - ? WHERE id = ?", allocated, productID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return allocated, nil
+}
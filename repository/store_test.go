@@ -0,0 +1,614 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"api-productnorder/currency"
+	"api-productnorder/dbtest"
+	"api-productnorder/models"
+)
+
+// forEachStore runs test against every Store implementation: the in-memory
+// store and the SQL store on every dialect dbtest can open. PostgreSQL and
+// MySQL run only when POSTGRES_TEST_URL and MYSQL_TEST_URL name throwaway
+// databases.
+func forEachStore(t *testing.T, test func(t *testing.T, s Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	for _, dialect := range dbtest.Dialects {
+		dialect := dialect
+		t.Run(dialect, func(t *testing.T) {
+			test(t, NewSQL(dbtest.Open(t, dialect), Dialect(dialect)))
+		})
+	}
+}
+
+func TestStoreCreateOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		widget, err := s.CreateProduct("Widget", 1000, currency.Default, nil, 5, 2, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gadget, err := s.CreateProduct("Gadget", 2500, currency.Default, nil, 1, 0, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Too little gadget stock rejects the whole order, widget included.
+		_, _, err = s.CreateOrder(NewOrder{Items: []OrderItem{
+			{ProductID: widget.ID, Quantity: 2},
+			{ProductID: gadget.ID, Quantity: 2},
+		}})
+		if !errors.Is(err, ErrInsufficientStock) {
+			t.Fatalf("got %v, want ErrInsufficientStock", err)
+		}
+		if product, _ := s.GetProductByID(widget.ID); product.Stock != 5 || product.Sold != 0 {
+			t.Fatalf("rejected order changed widget: stock %d, sold %d", product.Stock, product.Sold)
+		}
+
+		_, _, err = s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: 99, Quantity: 1}}})
+		if !errors.Is(err, ErrProductNotFound) {
+			t.Fatalf("got %v, want ErrProductNotFound", err)
+		}
+
+		_, _, err = s.CreateOrder(NewOrder{CustomerID: 99, Items: []OrderItem{{ProductID: widget.ID, Quantity: 1}}})
+		if !errors.Is(err, ErrCustomerNotFound) {
+			t.Fatalf("got %v, want ErrCustomerNotFound", err)
+		}
+
+		// Repeated items are merged into one line.
+		created, updated, err := s.CreateOrder(NewOrder{Items: []OrderItem{
+			{ProductID: widget.ID, Quantity: 1},
+			{ProductID: widget.ID, Quantity: 2},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(created.Lines) != 1 || created.Lines[0].Quantity != 3 {
+			t.Fatalf("got lines %+v, want one line of 3", created.Lines)
+		}
+		if len(updated) != 1 || updated[0].Stock != 2 || updated[0].Sold != 3 {
+			t.Fatalf("got updated products %+v, want stock 2, sold 3", updated)
+		}
+
+		id := *created.ID
+		order, err := s.GetOrderByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order.ID == nil || *order.ID != id || order.Status != models.OrderStatusPending || len(order.Lines) != 1 {
+			t.Fatalf("got order %+v", order)
+		}
+
+		missing, err := s.GetOrderByID(id + 1)
+		if err != nil || missing.ID != nil {
+			t.Fatalf("got %+v, %v for a missing order, want a zero order", missing, err)
+		}
+	})
+}
+
+// TestStoreTimestamps checks that timestamps are handed back in UTC, to the
+// second, and that what a write returns is what a later read sees.
+func TestStoreTimestamps(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		before := time.Now().UTC().Truncate(time.Second)
+		product, err := s.CreateProduct("Widget", 1000, currency.Default, nil, 5, 0, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := time.Now().UTC()
+
+		if product.CreatedAt.Location() != time.UTC || product.CreatedAt.Nanosecond() != 0 {
+			t.Fatalf("created_at %v, want UTC to the second", product.CreatedAt)
+		}
+		if product.CreatedAt.Before(before) || product.CreatedAt.After(after) {
+			t.Fatalf("created_at %v, want between %v and %v", product.CreatedAt, before, after)
+		}
+
+		stored, err := s.GetProductByID(product.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.CreatedAt.Equal(product.CreatedAt) || stored.CreatedAt.Location() != time.UTC {
+			t.Fatalf("read back created_at %v, want %v", stored.CreatedAt, product.CreatedAt)
+		}
+
+		created, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: product.ID, Quantity: 1}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, err := s.GetOrderByID(*created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !order.CreatedAt.Equal(*created.CreatedAt) || !order.UpdatedAt.Equal(*created.UpdatedAt) {
+			t.Fatalf("read back order timestamps %v, %v, want %v, %v", order.CreatedAt, order.UpdatedAt, created.CreatedAt, created.UpdatedAt)
+		}
+		if order.CreatedAt == nil || order.CreatedAt.Before(before) || order.CreatedAt.Location() != time.UTC {
+			t.Fatalf("order created_at %v, want a UTC time after %v", order.CreatedAt, before)
+		}
+
+		key, err := s.CreateAPIKey("ci", "abcd", "hash", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.TouchAPIKey(key.ID); err != nil {
+			t.Fatal(err)
+		}
+		keys, err := s.GetAllAPIKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0].LastUsedAt == nil || keys[0].RevokedAt != nil || keys[0].LastUsedAt.Before(before) {
+			t.Fatalf("got keys %+v, want one used, unrevoked key", keys)
+		}
+	})
+}
+
+func TestStoreUpdateOrderItems(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		widget, _ := s.CreateProduct("Widget", 1000, currency.Default, nil, 5, 0, models.StockPolicyReject)
+		gadget, _ := s.CreateProduct("Gadget", 2500, currency.Default, nil, 3, 0, models.StockPolicyReject)
+
+		created, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: widget.ID, Quantity: 4}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := *created.ID
+
+		_, err = s.UpdateOrderItems(id, []OrderItem{{ProductID: widget.ID, Quantity: 6}}, models.AllocationRequest{})
+		if !errors.Is(err, ErrInsufficientStock) {
+			t.Fatalf("got %v, want ErrInsufficientStock", err)
+		}
+
+		// A price change does not reprice the widgets already ordered.
+		current, _ := s.GetProductByID(widget.ID)
+		if _, err := s.UpdateProduct(widget.ID, "Widget", 1500, currency.Default, nil, current.Stock, 0, models.StockPolicyReject); err != nil {
+			t.Fatal(err)
+		}
+
+		// Swap most of the widgets for a gadget.
+		_, err = s.UpdateOrderItems(id, []OrderItem{
+			{ProductID: widget.ID, Quantity: 1},
+			{ProductID: gadget.ID, Quantity: 1},
+		}, models.AllocationRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if product, _ := s.GetProductByID(widget.ID); product.Stock != 4 || product.Sold != 1 {
+			t.Fatalf("widget: got stock %d, sold %d, want 4 and 1", product.Stock, product.Sold)
+		}
+		if product, _ := s.GetProductByID(gadget.ID); product.Stock != 2 || product.Sold != 1 {
+			t.Fatalf("gadget: got stock %d, sold %d, want 2 and 1", product.Stock, product.Sold)
+		}
+
+		order, _ := s.GetOrderByID(id)
+		if len(order.Lines) != 2 || order.Lines[0].Product.ID != widget.ID || order.Lines[1].Product.ID != gadget.ID {
+			t.Fatalf("got lines %+v, want widget then gadget", order.Lines)
+		}
+		if line := order.Lines[0]; line.UnitPrice != 1000 || line.LineTotal != 1000 || line.Product.Price != 1500 {
+			t.Fatalf("widget line: got %+v, want the price it was ordered at", line)
+		}
+		if line := order.Lines[1]; line.UnitPrice != 2500 || line.LineTotal != 2500 {
+			t.Fatalf("gadget line: got %+v, want the current price", line)
+		}
+
+		if _, err := s.UpdateOrderItems(id+1, nil, models.AllocationRequest{}); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("got %v, want ErrOrderNotFound", err)
+		}
+	})
+}
+
+func TestStoreCurrencies(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		kopi, _ := s.CreateProduct("Kopi", 25000, currency.Default, nil, 10, 0, models.StockPolicyReject)
+		luwak, err := s.CreateProduct("Luwak", 40, "USD", []models.ProductPrice{{Currency: currency.Default, Price: 650000}}, 10, 0, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		product, _ := s.GetProductByID(luwak.ID)
+		if product.Currency != "USD" || len(product.Prices) != 1 || product.Prices[0] != (models.ProductPrice{Currency: currency.Default, Price: 650000}) {
+			t.Fatalf("got %+v, want USD with an IDR price", product)
+		}
+
+		both := []OrderItem{{ProductID: kopi.ID, Quantity: 1}, {ProductID: luwak.ID, Quantity: 1}}
+		if _, _, err := s.CreateOrder(NewOrder{Items: both}); !errors.Is(err, ErrMixedCurrencies) {
+			t.Fatalf("got %v, want ErrMixedCurrencies", err)
+		}
+		if _, _, err := s.CreateOrder(NewOrder{Items: both, Currency: "USD"}); !errors.Is(err, ErrNoPriceInCurrency) {
+			t.Fatalf("got %v, want ErrNoPriceInCurrency", err)
+		}
+		if product, _ := s.GetProductByID(kopi.ID); product.Stock != 10 {
+			t.Fatalf("got stock %d after rejected orders, want 10", product.Stock)
+		}
+
+		idr, _, err := s.CreateOrder(NewOrder{Items: both, Currency: currency.Default})
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, _ := s.GetOrderByID(*idr.ID)
+		if order.Currency != currency.Default || len(order.Lines) != 2 || order.Lines[1].UnitPrice != 650000 {
+			t.Fatalf("got %+v, want the luwak priced from its IDR price", order)
+		}
+
+		usd, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: luwak.ID, Quantity: 2}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order, _ := s.GetOrderByID(*usd.ID); order.Currency != "USD" || order.Lines[0].LineTotal != 80 {
+			t.Fatalf("got %+v, want a USD order of 80", order)
+		}
+		if _, err := s.UpdateOrderItems(*usd.ID, both, models.AllocationRequest{}); !errors.Is(err, ErrNoPriceInCurrency) {
+			t.Fatalf("got %v, want ErrNoPriceInCurrency", err)
+		}
+
+		// Dropping the price list leaves the product with its base price only.
+		if product, _ := s.UpdateProduct(luwak.ID, "Luwak", 45, "USD", nil, 7, 0, models.StockPolicyReject); len(product.Prices) != 0 {
+			t.Fatalf("got prices %+v, want none", product.Prices)
+		}
+
+		sales, err := s.GetSalesByCurrency()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []models.SalesTotal{{Currency: currency.Default, Orders: 1, Total: 675000}, {Currency: "USD", Orders: 1, Total: 80}}
+		if len(sales) != 2 || sales[0] != want[0] || sales[1] != want[1] {
+			t.Fatalf("got sales %+v, want %+v", sales, want)
+		}
+
+		if _, err := s.SetExchangeRate("USD", 15000); err != nil {
+			t.Fatal(err)
+		}
+		rate, err := s.SetExchangeRate("USD", 16000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rates, err := s.GetExchangeRates()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rates) != 1 || rates[0] != rate || rates[0].Rate != 16000 {
+			t.Fatalf("got rates %+v, want only USD at 16000", rates)
+		}
+	})
+}
+
+func TestStoreBackorderAllocation(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		product, _ := s.CreateProduct("Widget", 1000, currency.Default, nil, 1, 0, models.StockPolicyBackorder)
+
+		first, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: product.ID, Quantity: 3}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: product.ID, Quantity: 2}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Three units arrive: the oldest order is filled first.
+		adjustment, _, err := s.AdjustStock(product.ID, 0, 3, "restock")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if adjustment.BackordersAllocated != 3 {
+			t.Fatalf("got %d units allocated, want 3", adjustment.BackordersAllocated)
+		}
+
+		order, _ := s.GetOrderByID(*first.ID)
+		if got := order.Lines[0].BackorderedQuantity; got != 0 {
+			t.Fatalf("first order: got %d backordered, want 0", got)
+		}
+		order, _ = s.GetOrderByID(*second.ID)
+		if got := order.Lines[0].BackorderedQuantity; got != 1 {
+			t.Fatalf("second order: got %d backordered, want 1", got)
+		}
+	})
+}
+
+func TestStoreDeleteCustomerKeepsOrders(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		product, _ := s.CreateProduct("Widget", 1000, currency.Default, nil, 5, 0, models.StockPolicyReject)
+		customer, err := s.CreateCustomer(models.Customer{Name: "Ana", Email: "ana@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CreateCustomer(models.Customer{Name: "Ana", Email: "ANA@example.com"}); !errors.Is(err, ErrDuplicateEmail) {
+			t.Fatalf("got %v, want ErrDuplicateEmail", err)
+		}
+
+		created, _, err := s.CreateOrder(NewOrder{CustomerID: customer.ID, Items: []OrderItem{{ProductID: product.ID, Quantity: 1}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := *created.ID
+		if orders, _ := s.GetOrdersByCustomer(customer.ID); len(orders) != 1 {
+			t.Fatalf("got %d customer orders, want 1", len(orders))
+		}
+
+		if _, err := s.DeleteCustomer(customer.ID); err != nil {
+			t.Fatal(err)
+		}
+		order, _ := s.GetOrderByID(id)
+		if order.ID == nil || order.CustomerID != nil {
+			t.Fatalf("got %+v, want the order kept without a customer", order)
+		}
+	})
+}
+
+func TestStoreDeleteProductKeepsOrderLines(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		product, _ := s.CreateProduct("Widget", 1000, currency.Default, nil, 5, 0, models.StockPolicyReject)
+		created, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: product.ID, Quantity: 2}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Later price changes must not show on the line either.
+		if _, err := s.UpdateProduct(product.ID, "Widget", 1500, currency.Default, nil, 3, 0, models.StockPolicyReject); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeleteProduct(product.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		want := models.OrderLine{Quantity: 2, UnitPrice: 1000, LineTotal: 2000}
+		check := func(order models.Order) {
+			t.Helper()
+			if len(order.Lines) != 1 {
+				t.Fatalf("got lines %+v, want one", order.Lines)
+			}
+			line := order.Lines[0]
+			if line.Product.ID != product.ID || line.Product.Name != "Widget" || line.Product.Price != 1000 || line.Product.Currency != currency.Default ||
+				line.Quantity != want.Quantity || line.UnitPrice != want.UnitPrice || line.LineTotal != want.LineTotal {
+				t.Fatalf("got line %+v, want %+v of the deleted widget", line, want)
+			}
+		}
+
+		order, err := s.GetOrderByID(*created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(order)
+
+		orders, err := s.GetOrders()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orders) != 1 {
+			t.Fatalf("got %d orders, want the order of the deleted product", len(orders))
+		}
+		check(orders[0])
+
+		// The line can be reduced and dropped, but not increased.
+		if _, err := s.UpdateOrderItems(*created.ID, []OrderItem{{ProductID: product.ID, Quantity: 3}}, models.AllocationRequest{}); !errors.Is(err, ErrProductNotFound) {
+			t.Fatalf("got %v, want ErrProductNotFound", err)
+		}
+		updated, err := s.UpdateOrderItems(*created.ID, []OrderItem{{ProductID: product.ID, Quantity: 1}}, models.AllocationRequest{})
+		if err != nil || len(updated) != 0 {
+			t.Fatalf("got %+v, %v, want no products", updated, err)
+		}
+		if order, _ := s.GetOrderByID(*created.ID); len(order.Lines) != 1 || order.Lines[0].Quantity != 1 {
+			t.Fatalf("got lines %+v, want one of 1", order.Lines)
+		}
+		if _, err := s.UpdateOrderItems(*created.ID, nil, models.AllocationRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		if order, _ := s.GetOrderByID(*created.ID); len(order.Lines) != 0 {
+			t.Fatalf("got lines %+v, want none", order.Lines)
+		}
+	})
+}
+
+// TestStoreConcurrentOrders places more orders than there is stock for at
+// once. Exactly as many must succeed as there are units.
+func TestStoreConcurrentOrders(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		const stock, buyers = 10, 30
+		product, err := s.CreateProduct("Widget", 1000, currency.Default, nil, stock, 0, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		results := make(chan error, buyers)
+		for i := 0; i < buyers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: product.ID, Quantity: 1}}})
+				results <- err
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		var placed int
+		for err := range results {
+			switch {
+			case err == nil:
+				placed++
+			case !errors.Is(err, ErrInsufficientStock):
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+		if placed != stock {
+			t.Errorf("placed %d orders, want %d", placed, stock)
+		}
+		if product, _ := s.GetProductByID(product.ID); product.Stock != 0 || product.Sold != stock {
+			t.Errorf("got stock %d, sold %d, want 0 and %d", product.Stock, product.Sold, stock)
+		}
+	})
+}
+
+// TestStoreConcurrentCrossedOrders places and edits orders listing the same
+// two products in opposite orders at once. Locking rows in the order the
+// request lists them would deadlock on MySQL and PostgreSQL.
+func TestStoreConcurrentCrossedOrders(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		const buyers = 20
+		a, _ := s.CreateProduct("A", 1000, currency.Default, nil, 100, 0, models.StockPolicyReject)
+		b, _ := s.CreateProduct("B", 1000, currency.Default, nil, 100, 0, models.StockPolicyReject)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 2*buyers)
+		for i := 0; i < buyers; i++ {
+			items := []OrderItem{{ProductID: a.ID, Quantity: 1}, {ProductID: b.ID, Quantity: 1}}
+			if i%2 == 1 {
+				items[0], items[1] = items[1], items[0]
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				order, _, err := s.CreateOrder(NewOrder{Items: items})
+				if err != nil {
+					errs <- err
+					return
+				}
+				reversed := []OrderItem{{ProductID: items[1].ProductID, Quantity: 2}, {ProductID: items[0].ProductID, Quantity: 2}}
+				if _, err := s.UpdateOrderItems(*order.ID, reversed, models.AllocationRequest{}); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Errorf("unexpected error: %v", err)
+		}
+		for _, id := range []int64{a.ID, b.ID} {
+			if product, _ := s.GetProductByID(id); product.Sold != 2*buyers {
+				t.Errorf("product %d: got sold %d, want %d", id, product.Sold, 2*buyers)
+			}
+		}
+	})
+}
+
+func TestStoreLowStockAlert(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		product, err := s.CreateProduct("Widget", 1000, currency.Default, nil, 1, 5, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// One alert per shortage, until the product is restocked.
+		for i, want := range []bool{true, false} {
+			marked, err := s.MarkLowStockAlerted(product.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if marked != want {
+				t.Fatalf("mark %d: got %v, want %v", i+1, marked, want)
+			}
+		}
+
+		if _, err := s.UpdateProduct(product.ID, "Widget", 1000, currency.Default, nil, 10, 5, models.StockPolicyReject); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateProduct(product.ID, "Widget", 1000, currency.Default, nil, 2, 5, models.StockPolicyReject); err != nil {
+			t.Fatal(err)
+		}
+		if marked, err := s.MarkLowStockAlerted(product.ID); err != nil || !marked {
+			t.Fatalf("after restocking: got %v, %v, want a new alert", marked, err)
+		}
+	})
+}
+
+// TestStoreLowStockAlertBackorders checks that a restock taken up by
+// backorders does not count as a restock for low stock alerts.
+func TestStoreLowStockAlertBackorders(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		product, _ := s.CreateProduct("Widget", 1000, currency.Default, nil, 0, 3, models.StockPolicyBackorder)
+		if _, _, err := s.CreateOrder(NewOrder{Items: []OrderItem{{ProductID: product.ID, Quantity: 5}}}); err != nil {
+			t.Fatal(err)
+		}
+		if marked, err := s.MarkLowStockAlerted(product.ID); err != nil || !marked {
+			t.Fatalf("got %v, %v, want an alert", marked, err)
+		}
+
+		if _, _, err := s.AdjustStock(product.ID, 0, 4, "restock"); err != nil {
+			t.Fatal(err)
+		}
+		if marked, err := s.MarkLowStockAlerted(product.ID); err != nil || marked {
+			t.Fatalf("after a restock filling backorders: got %v, %v, want no new alert", marked, err)
+		}
+
+		// Six units fill the last backorder and leave five in stock.
+		if _, _, err := s.AdjustStock(product.ID, 0, 6, "restock"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AdjustStock(product.ID, 0, -3, "shrinkage"); err != nil {
+			t.Fatal(err)
+		}
+		if marked, err := s.MarkLowStockAlerted(product.ID); err != nil || !marked {
+			t.Fatalf("after restocking: got %v, %v, want a new alert", marked, err)
+		}
+	})
+}
+
+func TestStoreUpdateProductKeepsWarehouseStock(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		product, err := s.CreateProduct("Widget", 1000, currency.Default, nil, 10, 0, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warehouse, err := s.CreateWarehouse(models.Warehouse{Code: "JKT", Name: "Jakarta"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.AdjustStock(product.ID, warehouse.ID, 4, "received"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = s.UpdateProduct(product.ID, "Widget", 1000, currency.Default, nil, 3, 0, models.StockPolicyReject)
+		if !errors.Is(err, ErrStockBelowWarehouses) {
+			t.Fatalf("stock below warehouse stock: got %v, want ErrStockBelowWarehouses", err)
+		}
+		if product, _ := s.GetProductByID(product.ID); product.Stock != 14 || product.Name != "Widget" {
+			t.Fatalf("rejected update changed the product: %+v", product)
+		}
+
+		updated, err := s.UpdateProduct(product.ID, "Widget", 1000, currency.Default, nil, 4, 0, models.StockPolicyReject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Stock != 4 {
+			t.Errorf("got stock %d, want 4", updated.Stock)
+		}
+	})
+}
+
+// TestStoreConcurrentExchangeRates sets the rate of a new currency from many
+// writers at once. None of them may fail on the row another one inserted.
+func TestStoreConcurrentExchangeRates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		const writers = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			rate := float64(15000 + i)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.SetExchangeRate("USD", rate); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if rates, _ := s.GetExchangeRates(); len(rates) != 1 {
+			t.Errorf("got rates %+v, want one for USD", rates)
+		}
+	})
+}
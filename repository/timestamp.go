@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// timeLayout is how timestamps are stored: in UTC, to the second. MySQL
+// DATETIME columns accept it, and in the TEXT columns of SQLite it sorts in
+// time order.
+const timeLayout = "2006-01-02 15:04:05"
+
+// nowUTC returns the current time as it will be stored, so that a timestamp
+// handed back after a write equals the one read back later.
+func nowUTC() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// bindArgs prepares time.Time arguments for the dialect. PostgreSQL takes
+// them as they are into its TIMESTAMPTZ columns; the others get text in
+// timeLayout, whatever their driver would make of a time.Time.
+func (d Dialect) bindArgs(args []interface{}) []interface{} {
+	format := func(t time.Time) interface{} {
+		if d == Postgres {
+			return t.UTC()
+		}
+		return t.UTC().Format(timeLayout)
+	}
+
+	bound := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			bound[i] = format(t)
+		case *time.Time:
+			if t != nil {
+				bound[i] = format(*t)
+			}
+		default:
+			bound[i] = arg
+		}
+	}
+	return bound
+}
+
+// scanTime scans a stored timestamp into t. MySQL opened with parseTime and
+// PostgreSQL return a time.Time; SQLite returns the stored text.
+func scanTime(t *time.Time) *timestamp {
+	return &timestamp{t: t}
+}
+
+// scanNullTime scans a nullable timestamp into t, leaving it nil for NULL.
+func scanNullTime(t **time.Time) *timestamp {
+	return &timestamp{null: t}
+}
+
+type timestamp struct {
+	t    *time.Time
+	null **time.Time
+}
+
+func (s *timestamp) Scan(src interface{}) error {
+	if src == nil {
+		if s.null == nil {
+			return fmt.Errorf("repository: NULL timestamp")
+		}
+		*s.null = nil
+		return nil
+	}
+
+	var t time.Time
+	switch v := src.(type) {
+	case time.Time:
+		t = v.UTC()
+	case string:
+		parsed, err := parseTime(v)
+		if err != nil {
+			return err
+		}
+		t = parsed
+	case []byte:
+		parsed, err := parseTime(string(v))
+		if err != nil {
+			return err
+		}
+		t = parsed
+	default:
+		return fmt.Errorf("repository: cannot scan %T into a timestamp", src)
+	}
+
+	if s.null != nil {
+		*s.null = &t
+	} else {
+		*s.t = t
+	}
+	return nil
+}
+
+// parseTime reads stored text as UTC. RFC 3339 is accepted too, for values
+// written by hand.
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("repository: parsing timestamp %q: %w", value, err)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"api-productnorder/models"
+	"database/sql"
+)
+
+func (s *SQL) GetAllWarehouses() ([]models.Warehouse, error) {
+	rows, err := s.db.Query("SELECT id, code, name, latitude, longitude, priority, created_at, updated_at FROM warehouses ORDER BY priority, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := []models.Warehouse{}
+	for rows.Next() {
+		var warehouse models.Warehouse
+		err := rows.Scan(&warehouse.ID, &warehouse.Code, &warehouse.Name, &warehouse.Latitude, &warehouse.Longitude, &warehouse.Priority, scanTime(&warehouse.CreatedAt), scanTime(&warehouse.UpdatedAt))
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	return warehouses, nil
+}
+
+func (s *SQL) CreateWarehouse(warehouse models.Warehouse) (models.Warehouse, error) {
+	createdAt := nowUTC()
+
+	id, err := s.db.Insert("INSERT INTO warehouses (code, name, latitude, longitude, priority, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		warehouse.Code, warehouse.Name, warehouse.Latitude, warehouse.Longitude, warehouse.Priority, createdAt, createdAt)
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+
+	warehouse.ID = id
+	warehouse.CreatedAt = createdAt
+	warehouse.UpdatedAt = createdAt
+	return warehouse, nil
+}
+
+func (s *SQL) GetWarehouseByID(id int64) (models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := s.db.QueryRow("SELECT id, code, name, latitude, longitude, priority, created_at, updated_at FROM warehouses WHERE id = ?", id).Scan(
+		&warehouse.ID, &warehouse.Code, &warehouse.Name, &warehouse.Latitude, &warehouse.Longitude, &warehouse.Priority, scanTime(&warehouse.CreatedAt), scanTime(&warehouse.UpdatedAt),
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Warehouse{}, nil
+		}
+		return models.Warehouse{}, err
+	}
+	return warehouse, nil
+}
+
+func (s *SQL) UpdateWarehouse(id int64, warehouse models.Warehouse) (models.Warehouse, error) {
+	updatedAt := nowUTC()
+	_, err := s.db.Exec("UPDATE warehouses SET code = ?, name = ?, latitude = ?, longitude = ?, priority = ?, updated_at = ? WHERE id = ?",
+		warehouse.Code, warehouse.Name, warehouse.Latitude, warehouse.Longitude, warehouse.Priority, updatedAt, id)
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+
+	return s.GetWarehouseByID(id)
+}
+
+// DeleteWarehouse removes an empty warehouse. It fails with
+// ErrWarehouseNotEmpty while the warehouse still holds stock.
+func (s *SQL) DeleteWarehouse(id int64) (models.Warehouse, error) {
+	warehouse, err := s.GetWarehouseByID(id)
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+
+	if warehouse.ID == 0 {
+		return models.Warehouse{}, nil
+	}
+
+	var stock int64
+	err = s.db.QueryRow("SELECT COALESCE(SUM(stock), 0) FROM warehouse_stock WHERE warehouse_id = ?", id).Scan(&stock)
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+	if stock > 0 {
+		return models.Warehouse{}, ErrWarehouseNotEmpty
+	}
+
+	_, err = s.db.Exec("DELETE FROM warehouses WHERE id = ?", id)
+	if err != nil {
+		return models.Warehouse{}, err
+	}
+
+	return warehouse, nil
+}
+
+// GetWarehouseStock returns a product's availability at each warehouse that stocks it.
+func (s *SQL) GetWarehouseStock(productID int64) ([]models.WarehouseStock, error) {
+	stocks, err := getWarehouseStock(s.db, "WHERE ws.product_id = ?", productID)
+	if err != nil {
+		return nil, err
+	}
+	return stocks[productID], nil
+}
+
+// GetAllWarehouseStock returns per-warehouse availability keyed by product ID.
+func (s *SQL) GetAllWarehouseStock() (map[int64][]models.WarehouseStock, error) {
+	return getWarehouseStock(s.db, "")
+}
+
+func getWarehouseStock(db *database, where string, args ...interface{}) (map[int64][]models.WarehouseStock, error) {
+	rows, err := db.Query(`
+		SELECT ws.product_id, w.id, w.code, w.name, ws.stock
+		FROM warehouse_stock ws
+		JOIN warehouses w ON ws.warehouse_id = w.id
+		`+where+`
+		ORDER BY ws.product_id, w.priority, w.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := make(map[int64][]models.WarehouseStock)
+	for rows.Next() {
+		var productID int64
+		var stock models.WarehouseStock
+		if err := rows.Scan(&productID, &stock.WarehouseID, &stock.Code, &stock.Name, &stock.Stock); err != nil {
+			return nil, err
+		}
+		stocks[productID] = append(stocks[productID], stock)
+	}
+
+	return stocks, rows.Err()
+}
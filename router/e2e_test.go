@@ -0,0 +1,409 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"api-productnorder/apiversion"
+	"api-productnorder/models"
+	"api-productnorder/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// e2eStep is one request in the end-to-end scenario. want is the message of
+// a JSON response or the text of an error response.
+type e2eStep struct {
+	route  string
+	method string
+	path   string
+	body   string
+	status int
+	want   string
+	check  func(t *testing.T, body []byte)
+}
+
+const (
+	validAddress   = `{"name": "Budi", "phone": "0812", "line1": "Jl. Merdeka 1", "city": "Jakarta", "province": "DKI Jakarta", "postal_code": "10110", "country": "ID"}`
+	invalidAddress = `{"name": "Budi", "line1": "Jl. Merdeka 1"}`
+)
+
+// productResponse is the envelope of a single product.
+type productResponse struct {
+	Data models.Product `json:"data"`
+}
+
+// productStock checks the stock and sold counts in a product detail response.
+func productStock(stock, sold int64) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		t.Helper()
+		var response productResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data.Stock != stock || response.Data.Sold != sold {
+			t.Errorf("stock %d, sold %d; want %d, %d", response.Data.Stock, response.Data.Sold, stock, sold)
+		}
+	}
+}
+
+// productPricing checks the currency and price list of a product response.
+func productPricing(currency string, prices ...models.ProductPrice) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		t.Helper()
+		var response productResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		got := response.Data
+		if got.Currency != currency || len(got.Prices) != len(prices) {
+			t.Fatalf("got %s %+v, want %s %+v", got.Currency, got.Prices, currency, prices)
+		}
+		for i := range prices {
+			if got.Prices[i] != prices[i] {
+				t.Errorf("got %s %+v, want %s %+v", got.Currency, got.Prices, currency, prices)
+			}
+		}
+	}
+}
+
+// productPolicy checks the stock policy of a product response.
+func productPolicy(policy string) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		t.Helper()
+		var response productResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data.StockPolicy != policy {
+			t.Errorf("stock policy %q, want %q", response.Data.StockPolicy, policy)
+		}
+	}
+}
+
+// productThreshold checks the reorder threshold of a product response.
+func productThreshold(threshold int64) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		t.Helper()
+		var response productResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data.ReorderThreshold != threshold {
+			t.Errorf("reorder threshold %d, want %d", response.Data.ReorderThreshold, threshold)
+		}
+	}
+}
+
+// orderQuantities checks the quantity ordered of each product in an order
+// response of either API version.
+func orderQuantities(want map[int64]int64) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		t.Helper()
+		var response struct {
+			Data struct {
+				Lines    []models.OrderLine       `json:"lines"`
+				Products []models.LegacyOrderLine `json:"products"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[int64]int64)
+		for _, line := range response.Data.Lines {
+			got[line.Product.ID] = line.Quantity
+		}
+		for _, line := range response.Data.Products {
+			got[line.ID] = line.Quantity
+		}
+		if len(got) != len(want) {
+			t.Errorf("order has %v, want %v", got, want)
+		}
+		for id, quantity := range want {
+			if got[id] != quantity {
+				t.Errorf("order has %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+// orderCurrency checks the currency of an order response of either API
+// version.
+func orderCurrency(want string) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		t.Helper()
+		var response struct {
+			Data struct {
+				Currency string `json:"currency"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data.Currency != want {
+			t.Errorf("order currency %q, want %q", response.Data.Currency, want)
+		}
+	}
+}
+
+// salesTotal checks the converted total and currency of a sales report.
+func salesTotal(currency string, total int64) func(t *testing.T, body []byte) {
+	return func(t *testing.T, body []byte) {
+		t.Helper()
+		var response struct {
+			Data models.SalesReport `json:"data"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Data.Currency != currency || response.Data.Total != total {
+			t.Errorf("sales report %s %d, want %s %d", response.Data.Currency, response.Data.Total, currency, total)
+		}
+	}
+}
+
+// e2eSteps walks every route, covering the happy path and the errors each
+// handler reports. Steps run in order against an empty store and build on
+// each other.
+func e2eSteps() []e2eStep {
+	return []e2eStep{
+		{route: "health.live", method: "GET", path: "/healthz", status: http.StatusOK},
+		{route: "health.ready", method: "GET", path: "/readyz", status: http.StatusOK},
+		{route: "docs.spec", method: "GET", path: "/openapi.json", status: http.StatusOK},
+		{route: "docs.ui", method: "GET", path: "/docs", status: http.StatusOK},
+		{route: "docs.assets", method: "GET", path: "/docs/missing.js", status: http.StatusNotFound},
+
+		// Products
+		{route: "products.list", method: "GET", path: "/api/products", status: http.StatusNotFound, want: "No products found"},
+		{route: "products.create", method: "POST", path: "/api/products", body: `{"name": `, status: http.StatusBadRequest, want: "Invalid request body"},
+		{route: "products.create", method: "POST", path: "/api/products", body: `{"name": "Kopi", "price": 25000, "stock": 5, "stock_policy": "sometimes"}`, status: http.StatusBadRequest, want: "Invalid stock policy"},
+		{route: "products.create", method: "POST", path: "/api/products", body: `{"name": "Kopi", "price": 25000, "stock": 5, "reorder_threshold": 2}`, status: http.StatusCreated, want: "Product created successfully"},
+		{route: "products.create", method: "POST", path: "/api/products", body: `{"name": "Teh", "price": 15000, "stock": 10}`, status: http.StatusCreated, want: "Product created successfully"},
+		{route: "products.create", method: "POST", path: "/api/products", body: `{"name": "Kopi Luwak", "price": 40, "currency": "usd", "stock": 5}`, status: http.StatusBadRequest, want: "Invalid currency"},
+		{route: "products.create", method: "POST", path: "/api/products", body: `{"name": "Kopi Luwak", "price": 40, "currency": "USD", "prices": [{"currency": "USD", "price": 40}], "stock": 5}`, status: http.StatusBadRequest, want: "Invalid prices"},
+		{route: "products.create", method: "POST", path: "/api/products", body: `{"name": "Kopi Luwak", "price": 40, "currency": "USD", "prices": [{"currency": "IDR", "price": 650000}], "stock": 5, "stock_policy": "backorder"}`, status: http.StatusCreated, want: "Product created successfully"},
+		{route: "products.update", method: "PUT", path: "/api/products/3", body: `{"name": "Kopi Luwak", "price": 40, "stock": 5}`, status: http.StatusOK, want: "Product updated successfully", check: productPolicy(models.StockPolicyBackorder)},
+		{route: "products.update", method: "PUT", path: "/api/products/3", body: `{"name": "Kopi Luwak", "price": 40, "stock": 5}`, status: http.StatusOK, want: "Product updated successfully", check: productPricing("USD", models.ProductPrice{Currency: "IDR", Price: 650000})},
+		{route: "products.update", method: "PUT", path: "/api/products/3", body: `{"name": "Kopi Luwak", "price": 40, "currency": "IDR", "stock": 5}`, status: http.StatusBadRequest, want: "Invalid prices"},
+		{route: "products.list", method: "GET", path: "/api/products", status: http.StatusOK, want: "Products retrieved successfully"},
+		{route: "products.list", method: "PATCH", path: "/api/products", status: http.StatusMethodNotAllowed},
+		{route: "products.detail", method: "GET", path: "/api/products/1", status: http.StatusOK, want: "Product Detail", check: productStock(5, 0)},
+		{route: "products.detail", method: "GET", path: "/api/products/99", status: http.StatusNotFound, want: "Product not found"},
+		{route: "products.detail", method: "GET", path: "/api/products/abc", status: http.StatusNotFound, want: "404 page not found"},
+		{route: "products.update", method: "PUT", path: "/api/products/1", body: `[]`, status: http.StatusBadRequest, want: "Invalid request body"},
+		{route: "products.update", method: "PUT", path: "/api/products/1", body: `{"name": "Kopi", "price": 25000, "stock": 5, "stock_policy": "sometimes"}`, status: http.StatusBadRequest, want: "Invalid stock policy"},
+		{route: "products.update", method: "PUT", path: "/api/products/99", body: `{"name": "Kopi", "price": 25000, "stock": 5}`, status: http.StatusNotFound, want: "Product not found"},
+		{route: "products.update", method: "PUT", path: "/api/products/1", body: `{"name": "Kopi Arabika", "price": 30000, "stock": 5, "reorder_threshold": 2}`, status: http.StatusOK, want: "Product updated successfully"},
+		{route: "products.update", method: "PUT", path: "/api/products/1", body: `{"name": "Kopi Arabika", "price": 30000, "stock": 5}`, status: http.StatusOK, want: "Product updated successfully", check: productThreshold(2)},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/1/stock-adjustments", body: `{"delta": `, status: http.StatusBadRequest, want: "Invalid request body"},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/1/stock-adjustments", body: `{"delta": 0, "reason": "count"}`, status: http.StatusBadRequest, want: "Delta must not be zero"},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/1/stock-adjustments", body: `{"delta": 5}`, status: http.StatusBadRequest, want: "Reason is required"},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/99/stock-adjustments", body: `{"delta": 5, "reason": "restock"}`, status: http.StatusNotFound, want: "Product not found"},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/1/stock-adjustments", body: `{"delta": 5, "reason": "restock", "warehouse_id": 99}`, status: http.StatusNotFound, want: "Warehouse not found"},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/1/stock-adjustments", body: `{"delta": -100, "reason": "shrinkage"}`, status: http.StatusConflict, want: "Stock cannot go below zero"},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/1/stock-adjustments", body: `{"delta": 5, "reason": "restock"}`, status: http.StatusCreated, want: "Stock adjusted successfully"},
+		{route: "products.detail", method: "GET", path: "/api/products/1", status: http.StatusOK, want: "Product Detail", check: productStock(10, 0)},
+		{route: "products.low-stock", method: "GET", path: "/api/products/low-stock", status: http.StatusOK, want: "Low stock products retrieved successfully"},
+
+		// Warehouses
+		{route: "warehouses.list", method: "GET", path: "/api/warehouses", status: http.StatusOK, want: "Warehouses retrieved successfully"},
+		{route: "warehouses.create", method: "POST", path: "/api/warehouses", body: `{"code": "JKT"}`, status: http.StatusBadRequest, want: "Code and name are required"},
+		{route: "warehouses.create", method: "POST", path: "/api/warehouses", body: `{"code": "JKT", "name": "Jakarta", "latitude": -6.2, "longitude": 106.8, "priority": 1}`, status: http.StatusCreated, want: "Warehouse created successfully"},
+		{route: "warehouses.detail", method: "GET", path: "/api/warehouses/1", status: http.StatusOK, want: "Warehouse Detail"},
+		{route: "warehouses.detail", method: "GET", path: "/api/warehouses/99", status: http.StatusNotFound, want: "Warehouse not found"},
+		{route: "warehouses.update", method: "PUT", path: "/api/warehouses/1", body: `{"code": "JKT", "name": "Jakarta Pusat", "latitude": -6.2, "longitude": 106.8, "priority": 1}`, status: http.StatusOK, want: "Warehouse updated successfully"},
+		{route: "warehouses.update", method: "PUT", path: "/api/warehouses/99", body: `{"code": "JKT", "name": "Jakarta", "latitude": -6.2, "longitude": 106.8}`, status: http.StatusNotFound, want: "Warehouse not found"},
+		// Stock held by a warehouse cannot be removed by a product update.
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/2/stock-adjustments", body: `{"delta": 2, "reason": "restock", "warehouse_id": 1}`, status: http.StatusCreated, want: "Stock adjusted successfully"},
+		{route: "products.update", method: "PUT", path: "/api/products/2", body: `{"name": "Teh", "price": 15000, "stock": 1}`, status: http.StatusConflict, want: "Stock cannot be less than the stock held by warehouses"},
+		{route: "products.adjust-stock", method: "POST", path: "/api/products/2/stock-adjustments", body: `{"delta": -2, "reason": "transfer", "warehouse_id": 1}`, status: http.StatusCreated, want: "Stock adjusted successfully"},
+
+		// Customers
+		{route: "customers.list", method: "GET", path: "/api/customers", status: http.StatusOK, want: "Customers retrieved successfully"},
+		{route: "customers.create", method: "POST", path: "/api/customers", body: `{"name": "Budi"}`, status: http.StatusBadRequest, want: "Name and email are required"},
+		{route: "customers.create", method: "POST", path: "/api/customers", body: `{"name": "Budi", "email": "budi@example.com"}`, status: http.StatusCreated, want: "Customer created successfully"},
+		{route: "customers.create", method: "POST", path: "/api/customers", body: `{"name": "Budi", "email": "BUDI@example.com"}`, status: http.StatusConflict, want: "Email already in use"},
+		{route: "customers.detail", method: "GET", path: "/api/customers/1", status: http.StatusOK, want: "Customer Detail"},
+		{route: "customers.detail", method: "GET", path: "/api/customers/99", status: http.StatusNotFound, want: "Customer not found"},
+		{route: "customers.update", method: "PUT", path: "/api/customers/1", body: `{"name": "Budi Santoso", "email": "budi@example.com"}`, status: http.StatusOK, want: "Customer updated successfully"},
+		{route: "customers.update", method: "PUT", path: "/api/customers/99", body: `{"name": "Budi", "email": "budi@example.com"}`, status: http.StatusNotFound, want: "Customer not found"},
+
+		// Orders
+		{route: "orders.list", method: "GET", path: "/api/orders", status: http.StatusOK, want: "Order List"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": `, status: http.StatusBadRequest, want: "Invalid request body"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": []}`, status: http.StatusBadRequest, want: "Order must contain at least one product"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 0}]}`, status: http.StatusBadRequest, want: "Quantity must be greater than zero"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}], "allocation": {"rule": "random"}}`, status: http.StatusBadRequest, want: "Invalid allocation rule"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}], "allocation": {"rule": "nearest"}}`, status: http.StatusBadRequest, want: "Latitude and longitude are required for nearest allocation"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}], "shipping_address": ` + invalidAddress + `}`, status: http.StatusBadRequest, want: "Addresses require name, line1, city, postal_code and country"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}], "customer_id": 99}`, status: http.StatusNotFound, want: "Customer not found"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 99, "quantity": 1}]}`, status: http.StatusNotFound, want: "Product not found"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 11}]}`, status: http.StatusBadRequest, want: "Product out of stock"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 6}, {"id": 1, "quantity": 5}]}`, status: http.StatusBadRequest, want: "Product out of stock"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}], "currency": "usd"}`, status: http.StatusBadRequest, want: "Invalid currency"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}, {"id": 3, "quantity": 1}]}`, status: http.StatusBadRequest, want: "Products in an order must share one currency"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}], "currency": "USD"}`, status: http.StatusBadRequest, want: "Product has no price in the order currency"},
+		{route: "products.detail", method: "GET", path: "/api/products/1", status: http.StatusOK, want: "Product Detail", check: productStock(10, 0)},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}, {"id": 1, "quantity": 1}], "customer_id": 1, "shipping_address": ` + validAddress + `}`, status: http.StatusOK, want: "Order created", check: orderQuantities(map[int64]int64{1: 2})},
+		{route: "products.detail", method: "GET", path: "/api/products/1", status: http.StatusOK, want: "Product Detail", check: productStock(8, 2)},
+		{route: "orders.detail", method: "GET", path: "/api/orders/1", status: http.StatusOK, want: "Order Detail", check: orderQuantities(map[int64]int64{1: 2})},
+		{route: "orders.detail", method: "GET", path: "/api/orders/abc", status: http.StatusBadRequest, want: "Invalid order ID"},
+		{route: "orders.detail", method: "GET", path: "/api/orders/99", status: http.StatusNotFound, want: "Order not found"},
+		{route: "customers.orders", method: "GET", path: "/api/customers/1/orders", status: http.StatusOK, want: "Order List"},
+		{route: "customers.orders", method: "GET", path: "/api/customers/99/orders", status: http.StatusNotFound, want: "Customer not found"},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/1/items", body: `{"products": `, status: http.StatusBadRequest, want: "Invalid request body"},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/1/items", body: `{"products": [{"id": 1, "quantity": -1}]}`, status: http.StatusBadRequest, want: "Quantity must not be negative"},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/1/items", body: `{"products": [{"id": 1, "quantity": 0}]}`, status: http.StatusBadRequest, want: "Order must contain at least one product"},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/99/items", body: `{"products": [{"id": 1, "quantity": 1}]}`, status: http.StatusNotFound, want: "Order not found"},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/1/items", body: `{"products": [{"id": 99, "quantity": 1}]}`, status: http.StatusNotFound, want: "Product not found"},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/1/items", body: `{"products": [{"id": 1, "quantity": 11}]}`, status: http.StatusBadRequest, want: "Product out of stock"},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 6}, {"id": 1, "quantity": 5}]}`, status: http.StatusBadRequest, want: "Product out of stock"},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/1/items", body: `{"products": [{"id": 1, "quantity": 3}, {"id": 2, "quantity": 1}]}`, status: http.StatusOK, want: "Order updated successfully", check: orderQuantities(map[int64]int64{1: 3, 2: 1})},
+		{route: "products.detail", method: "GET", path: "/api/products/1", status: http.StatusOK, want: "Product Detail", check: productStock(7, 3)},
+		{route: "products.detail", method: "GET", path: "/api/products/2", status: http.StatusOK, want: "Product Detail", check: productStock(9, 1)},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 3, "quantity": 1}]}`, status: http.StatusOK, want: "Order created", check: orderCurrency("USD")},
+		{route: "orders.create", method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}, {"id": 3, "quantity": 1}], "currency": "IDR"}`, status: http.StatusOK, want: "Order created", check: orderCurrency("IDR")},
+		{route: "orders.update-items", method: "PUT", path: "/api/orders/2/items", body: `{"products": [{"id": 1, "quantity": 1}, {"id": 3, "quantity": 1}]}`, status: http.StatusBadRequest, want: "Product has no price in the order currency"},
+		{route: "orders.update-addresses", method: "PUT", path: "/api/orders/1/addresses", body: `{"shipping_address": `, status: http.StatusBadRequest, want: "Invalid request body"},
+		{route: "orders.update-addresses", method: "PUT", path: "/api/orders/1/addresses", body: `{}`, status: http.StatusBadRequest, want: "Shipping or billing address is required"},
+		{route: "orders.update-addresses", method: "PUT", path: "/api/orders/1/addresses", body: `{"billing_address": ` + invalidAddress + `}`, status: http.StatusBadRequest, want: "Addresses require name, line1, city, postal_code and country"},
+		{route: "orders.update-addresses", method: "PUT", path: "/api/orders/99/addresses", body: `{"billing_address": ` + validAddress + `}`, status: http.StatusNotFound, want: "Order not found"},
+		{route: "orders.update-addresses", method: "PUT", path: "/api/orders/1/addresses", body: `{"billing_address": ` + validAddress + `}`, status: http.StatusOK, want: "Order updated successfully"},
+		{route: "orders.delete", method: "DELETE", path: "/api/orders/abc", status: http.StatusBadRequest, want: "Invalid order ID"},
+		{route: "orders.delete", method: "DELETE", path: "/api/orders/99", status: http.StatusNotFound, want: "Order not found"},
+		{route: "orders.delete", method: "DELETE", path: "/api/orders/1", status: http.StatusOK, want: "Order deleted successfully"},
+		{route: "orders.detail", method: "GET", path: "/api/orders/1", status: http.StatusNotFound, want: "Order not found"},
+
+		// Reports. Order 2 is 40 USD; order 3 is 30000 + 650000 IDR.
+		{route: "exchange-rates.list", method: "GET", path: "/api/exchange-rates", status: http.StatusOK, want: "Exchange rates retrieved successfully"},
+		{route: "reports.sales", method: "GET", path: "/api/reports/sales", status: http.StatusConflict, want: "Missing exchange rate: no exchange rate for USD"},
+		{route: "exchange-rates.update", method: "PUT", path: "/api/admin/exchange-rates/usd", body: `{"rate": 16000}`, status: http.StatusBadRequest, want: "Invalid currency"},
+		{route: "exchange-rates.update", method: "PUT", path: "/api/admin/exchange-rates/IDR", body: `{"rate": 2}`, status: http.StatusBadRequest, want: "The default currency has a fixed rate of 1"},
+		{route: "exchange-rates.update", method: "PUT", path: "/api/admin/exchange-rates/USD", body: `{"rate": 0}`, status: http.StatusBadRequest, want: "Rate must be greater than zero"},
+		{route: "exchange-rates.update", method: "PUT", path: "/api/admin/exchange-rates/USD", body: `{"rate": 16000}`, status: http.StatusOK, want: "Exchange rate updated successfully"},
+		{route: "reports.sales", method: "GET", path: "/api/reports/sales?currency=usd", status: http.StatusBadRequest, want: "Invalid currency"},
+		{route: "reports.sales", method: "GET", path: "/api/reports/sales", status: http.StatusOK, want: "Sales report", check: salesTotal("IDR", 1320000)},
+		{route: "reports.sales", method: "GET", path: "/api/reports/sales?currency=USD", status: http.StatusOK, want: "Sales report", check: salesTotal("USD", 83)},
+
+		// API keys
+		{route: "api-keys.list", method: "GET", path: "/api/admin/api-keys", status: http.StatusOK, want: "API keys retrieved successfully"},
+		{route: "api-keys.create", method: "POST", path: "/api/admin/api-keys", body: `{"name": "scanner"}`, status: http.StatusBadRequest, want: "At least one scope is required"},
+		{route: "api-keys.create", method: "POST", path: "/api/admin/api-keys", body: `{"name": "scanner", "scopes": ["products:read"]}`, status: http.StatusCreated, want: "API key created successfully"},
+		{route: "api-keys.revoke", method: "DELETE", path: "/api/admin/api-keys/99", status: http.StatusNotFound, want: "API key not found"},
+		{route: "api-keys.revoke", method: "DELETE", path: "/api/admin/api-keys/1", status: http.StatusOK, want: "API key revoked successfully"},
+
+		// Deletes last, once nothing else needs the rows.
+		{route: "customers.delete", method: "DELETE", path: "/api/customers/99", status: http.StatusNotFound, want: "Customer not found"},
+		{route: "customers.delete", method: "DELETE", path: "/api/customers/1", status: http.StatusOK, want: "Customer deleted successfully"},
+		{route: "warehouses.delete", method: "DELETE", path: "/api/warehouses/99", status: http.StatusNotFound, want: "Warehouse not found"},
+		{route: "warehouses.delete", method: "DELETE", path: "/api/warehouses/1", status: http.StatusOK, want: "Warehouse deleted successfully"},
+		{route: "products.delete", method: "DELETE", path: "/api/products/99", status: http.StatusNotFound, want: "Product not found"},
+		{route: "products.delete", method: "DELETE", path: "/api/products/2", status: http.StatusOK, want: "Product deleted successfully"},
+		{route: "products.detail", method: "GET", path: "/api/products/2", status: http.StatusNotFound, want: "Product not found"},
+	}
+}
+
+// run sends the step's request to r and checks the response.
+func (step e2eStep) run(t *testing.T, r http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != step.status {
+		t.Fatalf("%s %s: got %d %q, want %d", step.method, step.path, rec.Code, rec.Body.String(), step.status)
+	}
+	if step.want != "" {
+		if got := responseMessage(t, rec); got != step.want {
+			t.Errorf("%s %s: got message %q, want %q", step.method, step.path, got, step.want)
+		}
+	}
+	if step.check != nil {
+		step.check(t, rec.Body.Bytes())
+	}
+	return rec
+}
+
+// apiVersions names the versions the end-to-end scenario runs under. The
+// empty name sends no API-Version header.
+var apiVersions = map[string]string{
+	"default": "",
+	"v1":      "1",
+	"v2":      "2",
+}
+
+// withAPIVersion sends every request to next with the API-Version header set
+// to version, unless version is empty.
+func withAPIVersion(version string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version != "" {
+			r.Header.Set(apiversion.Header, version)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TestEndToEnd runs the end-to-end scenario through the real router against
+// an in-memory store, once for each API version.
+func TestEndToEnd(t *testing.T) {
+	for name, version := range apiVersions {
+		version := version
+		t.Run(name, func(t *testing.T) {
+			r := New(Config{Store: repository.NewMemory()})
+			h := withAPIVersion(version, r)
+
+			want := version
+			if want == "" {
+				want = strconv.Itoa(apiversion.Default)
+			}
+			for _, step := range e2eSteps() {
+				rec := step.run(t, h)
+				// Requests that match no route never reach the middleware.
+				if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") && rec.Header().Get(apiversion.Header) != want {
+					t.Errorf("%s %s: %s %q, want %q", step.method, step.path, apiversion.Header, rec.Header().Get(apiversion.Header), want)
+				}
+			}
+		})
+	}
+
+	r := New(Config{})
+	for _, version := range []string{"0", "3", "v2"} {
+		step := e2eStep{method: "GET", path: "/healthz", status: http.StatusBadRequest, want: "Unsupported API version"}
+		step.run(t, withAPIVersion(version, r))
+	}
+
+	covered := make(map[string]bool)
+	for _, step := range e2eSteps() {
+		covered[step.route] = true
+	}
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if name := route.GetName(); !covered[name] {
+			t.Errorf("route %q has no end-to-end test", name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// responseMessage returns the message of a JSON response or the text of an
+// error response.
+func responseMessage(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		return strings.TrimSpace(rec.Body.String())
+	}
+	var response struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return response.Message
+}
@@ -0,0 +1,341 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"api-productnorder/openapi"
+	"api-productnorder/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// spec is the part of an OpenAPI document the drift tests read. Schemas are
+// kept as decoded JSON and checked by validate.
+type spec struct {
+	OpenAPI    string                          `json:"openapi"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Responses map[string]response    `json:"responses"`
+		Schemas   map[string]interface{} `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string              `json:"operationId"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type response struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema interface{} `json:"schema"`
+	} `json:"content"`
+}
+
+func loadSpec(t *testing.T) *spec {
+	t.Helper()
+	var s spec
+	if err := json.Unmarshal(openapi.Spec, &s); err != nil {
+		t.Fatalf("decoding openapi.json: %v", err)
+	}
+	if s.OpenAPI != "3.1.0" {
+		t.Fatalf("openapi version %q, want 3.1.0", s.OpenAPI)
+	}
+	return &s
+}
+
+// operationFor returns the method, path and operation documenting the route
+// with the given name.
+func (s *spec) operationFor(route string) (string, string, operation, bool) {
+	for path, methods := range s.Paths {
+		for method, op := range methods {
+			if op.OperationID == route {
+				return strings.ToUpper(method), path, op, true
+			}
+		}
+	}
+	return "", "", operation{}, false
+}
+
+// response returns the documented response for status, following a $ref
+// into components.
+func (s *spec) response(op operation, status int) (response, bool) {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if ok && resp.Ref != "" {
+		resp, ok = s.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+	}
+	return resp, ok
+}
+
+var pathVariable = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+// TestOpenAPICoversRoutes checks that the spec documents every route under
+// its name, with the route's method and path, and nothing else.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	s := loadSpec(t)
+	r := New(Config{})
+
+	documented := make(map[string]bool)
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		name := route.GetName()
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		method, path, _, ok := s.operationFor(name)
+		if !ok {
+			t.Errorf("route %q is not in the spec", name)
+			return nil
+		}
+		documented[name] = true
+		if want := pathVariable.ReplaceAllString(template, "{$1}"); path != want {
+			t.Errorf("route %q: spec path %s, want %s", name, path, want)
+		}
+		if len(methods) != 1 || method != methods[0] {
+			t.Errorf("route %q: spec method %s, want %v", name, method, methods)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, methods := range s.Paths {
+		for method, op := range methods {
+			if !documented[op.OperationID] {
+				t.Errorf("%s %s (%q) has no route", strings.ToUpper(method), path, op.OperationID)
+			}
+		}
+	}
+}
+
+// TestResponsesMatchOpenAPI runs the end-to-end scenario under every API
+// version and checks every response against the spec: the status must be
+// documented for the operation, and JSON bodies must match the documented
+// schema exactly.
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	s := loadSpec(t)
+	for name, version := range apiVersions {
+		version := version
+		t.Run(name, func(t *testing.T) {
+			s.checkResponses(t, withAPIVersion(version, New(Config{Store: repository.NewMemory()})))
+		})
+	}
+}
+
+func (s *spec) checkResponses(t *testing.T, r http.Handler) {
+	for _, step := range e2eSteps() {
+		rec := step.run(t, r)
+
+		method, _, op, ok := s.operationFor(step.route)
+		if !ok {
+			t.Fatalf("route %q is not in the spec", step.route)
+		}
+		// Requests the router turns away before reaching a route, such as a
+		// wrong method, are not part of any operation.
+		if method != step.method {
+			continue
+		}
+
+		resp, ok := s.response(op, rec.Code)
+		if !ok {
+			t.Errorf("%s %s: status %d is not documented for %s", step.method, step.path, rec.Code, step.route)
+			continue
+		}
+
+		mediaType := strings.TrimSpace(strings.Split(rec.Header().Get("Content-Type"), ";")[0])
+		content, ok := resp.Content[mediaType]
+		if !ok {
+			t.Errorf("%s %s: content type %q is not documented for status %d", step.method, step.path, mediaType, rec.Code)
+			continue
+		}
+		if mediaType != "application/json" {
+			continue
+		}
+
+		var body interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s %s: decoding response: %v", step.method, step.path, err)
+			continue
+		}
+		for _, err := range s.validate(content.Schema, body, "$") {
+			t.Errorf("%s %s: %v", step.method, step.path, err)
+		}
+	}
+}
+
+// validate checks value against the subset of JSON Schema the spec uses:
+// $ref, oneOf, type (including type lists for nullable values), the
+// date-time format, enum, properties, required, additionalProperties and
+// items.
+func (s *spec) validate(schema interface{}, value interface{}, at string) []error {
+	rules, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if ref, ok := rules["$ref"].(string); ok {
+		return s.validate(s.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")], value, at)
+	}
+	if alternatives, ok := rules["oneOf"].([]interface{}); ok {
+		var matched int
+		var errs []error
+		for _, alternative := range alternatives {
+			altErrs := s.validate(alternative, value, at)
+			if len(altErrs) == 0 {
+				matched++
+			}
+			errs = append(errs, altErrs...)
+		}
+		switch matched {
+		case 0:
+			return errs
+		case 1:
+			return nil
+		default:
+			return []error{fmt.Errorf("%s: matches %d schemas of oneOf, want exactly one", at, matched)}
+		}
+	}
+
+	if types := schemaTypes(rules["type"]); len(types) > 0 {
+		matched := false
+		for _, typ := range types {
+			if hasType(value, typ) {
+				matched = true
+			}
+		}
+		if !matched {
+			return []error{fmt.Errorf("%s: got %s, want %s", at, jsonType(value), strings.Join(types, " or "))}
+		}
+	}
+
+	if rules["format"] == "date-time" {
+		if text, ok := value.(string); ok {
+			if _, err := time.Parse(time.RFC3339, text); err != nil {
+				return []error{fmt.Errorf("%s: %q is not an RFC 3339 date-time", at, text)}
+			}
+		}
+	}
+
+	if enum, ok := rules["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if allowed == value {
+				found = true
+			}
+		}
+		if !found {
+			return []error{fmt.Errorf("%s: %v is not one of %v", at, value, enum)}
+		}
+	}
+
+	var errs []error
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := rules["properties"].(map[string]interface{})
+		if required, ok := rules["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					errs = append(errs, fmt.Errorf("%s: missing required property %q", at, name))
+				}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := properties[name]; ok {
+				errs = append(errs, s.validate(property, v[name], at+"."+name)...)
+				continue
+			}
+			switch additional := rules["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					errs = append(errs, fmt.Errorf("%s: property %q is not in the spec", at, name))
+				}
+			case map[string]interface{}:
+				errs = append(errs, s.validate(additional, v[name], at+"."+name)...)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			errs = append(errs, s.validate(rules["items"], item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	}
+	return errs
+}
+
+func schemaTypes(typ interface{}) []string {
+	switch t := typ.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, len(t))
+		for i, name := range t {
+			types[i], _ = name.(string)
+		}
+		return types
+	}
+	return nil
+}
+
+func hasType(value interface{}, typ string) bool {
+	switch typ {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == typ
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// TestOpenAPIServed checks that the spec and the Swagger UI page are served.
+func TestOpenAPIServed(t *testing.T) {
+	r := New(Config{})
+
+	rec := (e2eStep{method: "GET", path: "/openapi.json", status: http.StatusOK}).run(t, r)
+	if rec.Body.String() != string(openapi.Spec) {
+		t.Error("/openapi.json does not serve the embedded spec")
+	}
+
+	rec = (e2eStep{method: "GET", path: "/docs", status: http.StatusOK}).run(t, r)
+	if !strings.Contains(rec.Body.String(), `url: "/openapi.json"`) {
+		t.Error("/docs does not load /openapi.json")
+	}
+}
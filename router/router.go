@@ -0,0 +1,178 @@
+// Package router registers the API routes and their middleware.
+package router
+
+import (
+	"api-productnorder/apiversion"
+	"api-productnorder/auth"
+	"api-productnorder/handlers"
+	"api-productnorder/health"
+	"api-productnorder/idempotency"
+	"api-productnorder/openapi"
+	"api-productnorder/ratelimit"
+	"api-productnorder/repository"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// PublicRoutes names the routes that can be called without a token.
+var PublicRoutes = []string{
+	"health.live",
+	"health.ready",
+	"docs.spec",
+	"docs.ui",
+	"docs.assets",
+	"products.list",
+	"products.detail",
+}
+
+// DefaultRateLimits are the per-client limits for each route and the limit
+// per address. Writes that move stock or money are held to tighter limits
+// than reads.
+var DefaultRateLimits = ratelimit.Config{
+	Default: ratelimit.Limit{Requests: 120, Period: time.Minute},
+	// Several clients may share an address behind NAT, so the limit per
+	// address is well above the limit per client.
+	IP: ratelimit.Limit{Requests: 600, Period: time.Minute},
+	Routes: map[string]ratelimit.Limit{
+		// Probes are not limited, so an orchestrator polling from one
+		// address never sees the server as unhealthy.
+		"health.live":  {},
+		"health.ready": {},
+
+		"products.create":         {Requests: 30, Period: time.Minute},
+		"products.update":         {Requests: 30, Period: time.Minute},
+		"products.delete":         {Requests: 10, Period: time.Minute},
+		"products.adjust-stock":   {Requests: 60, Period: time.Minute},
+		"warehouses.create":       {Requests: 10, Period: time.Minute},
+		"warehouses.update":       {Requests: 10, Period: time.Minute},
+		"warehouses.delete":       {Requests: 10, Period: time.Minute},
+		"customers.create":        {Requests: 30, Period: time.Minute},
+		"customers.update":        {Requests: 30, Period: time.Minute},
+		"customers.delete":        {Requests: 10, Period: time.Minute},
+		"orders.create":           {Requests: 10, Period: time.Minute},
+		"orders.delete":           {Requests: 10, Period: time.Minute},
+		"orders.update-items":     {Requests: 20, Period: time.Minute},
+		"orders.update-addresses": {Requests: 20, Period: time.Minute},
+		"api-keys.create":         {Requests: 5, Period: time.Minute},
+		"api-keys.revoke":         {Requests: 10, Period: time.Minute},
+	},
+}
+
+// Config wires the storage and middleware behind the API routes.
+type Config struct {
+	// Store backs the handlers. Nil serves the API from an empty in-memory
+	// store, which is enough for tests and demos.
+	Store repository.Store
+	// Auth validates bearer tokens. Nil disables token authentication.
+	Auth *auth.Validator
+	// APIKeys resolves X-API-Key headers. Nil disables API key authentication.
+	APIKeys auth.APIKeyStore
+	// RateLimitStore holds the rate limit buckets. Nil disables rate limiting.
+	RateLimitStore ratelimit.Store
+	// RateLimits sets the limit for each route.
+	RateLimits ratelimit.Config
+	// IdempotencyStore holds responses replayed for Idempotency-Key retries.
+	// Nil disables idempotency keys.
+	IdempotencyStore idempotency.Store
+	// IdempotencyTTL is how long an idempotency key is remembered.
+	IdempotencyTTL time.Duration
+	// Health serves /healthz and /readyz. Nil serves them without any
+	// readiness checks.
+	Health *health.Checker
+}
+
+// New returns the router serving every API route.
+func New(cfg Config) *mux.Router {
+	r := mux.NewRouter()
+
+	checker := cfg.Health
+	if checker == nil {
+		checker = health.New()
+	}
+	store := cfg.Store
+	if store == nil {
+		store = repository.NewMemory()
+	}
+	h := handlers.New(store)
+
+	r.HandleFunc("/healthz", checker.LiveHandler).Methods("GET").Name("health.live")
+	r.HandleFunc("/readyz", checker.ReadyHandler).Methods("GET").Name("health.ready")
+
+	r.HandleFunc("/openapi.json", openapi.SpecHandler).Methods("GET").Name("docs.spec")
+	r.HandleFunc("/docs", openapi.UIHandler).Methods("GET").Name("docs.ui")
+	r.Handle("/docs/{file:[a-z-]+\\.(?:css|js)}", openapi.AssetHandler).Methods("GET").Name("docs.assets")
+
+	r.HandleFunc("/api/products", h.GetProductsHandler).Methods("GET").Name("products.list")
+	r.HandleFunc("/api/products", h.CreateProductHandler).Methods("POST").Name("products.create")
+	r.HandleFunc("/api/products/low-stock", h.GetLowStockProductsHandler).Methods("GET").Name("products.low-stock")
+	r.HandleFunc("/api/products/{id:[0-9]+}", h.GetProductDetailHandler).Methods("GET").Name("products.detail")
+	r.HandleFunc("/api/products/{id:[0-9]+}", h.UpdateProductHandler).Methods("PUT").Name("products.update")
+	r.HandleFunc("/api/products/{id:[0-9]+}", h.DeleteProductHandler).Methods("DELETE").Name("products.delete")
+	r.HandleFunc("/api/products/{id:[0-9]+}/stock-adjustments", h.AdjustProductStockHandler).Methods("POST").Name("products.adjust-stock")
+
+	r.HandleFunc("/api/warehouses", h.GetWarehousesHandler).Methods("GET").Name("warehouses.list")
+	r.HandleFunc("/api/warehouses", h.CreateWarehouseHandler).Methods("POST").Name("warehouses.create")
+	r.HandleFunc("/api/warehouses/{id:[0-9]+}", h.GetWarehouseDetailHandler).Methods("GET").Name("warehouses.detail")
+	r.HandleFunc("/api/warehouses/{id:[0-9]+}", h.UpdateWarehouseHandler).Methods("PUT").Name("warehouses.update")
+	r.HandleFunc("/api/warehouses/{id:[0-9]+}", h.DeleteWarehouseHandler).Methods("DELETE").Name("warehouses.delete")
+
+	r.HandleFunc("/api/customers", h.GetCustomersHandler).Methods("GET").Name("customers.list")
+	r.HandleFunc("/api/customers", h.CreateCustomerHandler).Methods("POST").Name("customers.create")
+	r.HandleFunc("/api/customers/{id:[0-9]+}", h.GetCustomerDetailHandler).Methods("GET").Name("customers.detail")
+	r.HandleFunc("/api/customers/{id:[0-9]+}", h.UpdateCustomerHandler).Methods("PUT").Name("customers.update")
+	r.HandleFunc("/api/customers/{id:[0-9]+}", h.DeleteCustomerHandler).Methods("DELETE").Name("customers.delete")
+	r.HandleFunc("/api/customers/{id:[0-9]+}/orders", h.GetCustomerOrdersHandler).Methods("GET").Name("customers.orders")
+
+	r.HandleFunc("/api/orders", h.GetOrdersHandler).Methods("GET").Name("orders.list")
+	r.HandleFunc("/api/orders", h.CreateOrderHandler).Methods("POST").Name("orders.create")
+	r.HandleFunc("/api/orders/{id}", h.GetOrderDetailHandler).Methods("GET").Name("orders.detail")
+	r.HandleFunc("/api/orders/{id}", h.DeleteOrderHandler).Methods("DELETE").Name("orders.delete")
+	r.HandleFunc("/api/orders/{id:[0-9]+}/items", h.UpdateOrderItemsHandler).Methods("PUT").Name("orders.update-items")
+	r.HandleFunc("/api/orders/{id:[0-9]+}/addresses", h.UpdateOrderAddressesHandler).Methods("PUT").Name("orders.update-addresses")
+
+	r.HandleFunc("/api/admin/api-keys", h.GetAPIKeysHandler).Methods("GET").Name("api-keys.list")
+	r.HandleFunc("/api/admin/api-keys", h.CreateAPIKeyHandler).Methods("POST").Name("api-keys.create")
+	r.HandleFunc("/api/admin/api-keys/{id:[0-9]+}", h.RevokeAPIKeyHandler).Methods("DELETE").Name("api-keys.revoke")
+
+	r.HandleFunc("/api/exchange-rates", h.GetExchangeRatesHandler).Methods("GET").Name("exchange-rates.list")
+	r.HandleFunc("/api/admin/exchange-rates/{currency}", h.SetExchangeRateHandler).Methods("PUT").Name("exchange-rates.update")
+	r.HandleFunc("/api/reports/sales", h.GetSalesReportHandler).Methods("GET").Name("reports.sales")
+
+	// The version is settled first so that every response, errors from the
+	// middleware below included, names the version it was served in.
+	r.Use(apiversion.Middleware)
+
+	// Addresses are limited before authentication, which looks up API keys
+	// in the store, so that bad credentials cannot be tried without limit.
+	if cfg.RateLimitStore != nil {
+		r.Use(ratelimit.IPMiddleware(cfg.RateLimitStore, cfg.RateLimits))
+	}
+
+	// Without any authentication method configured every route is open.
+	if cfg.Auth != nil || cfg.APIKeys != nil {
+		r.Use(auth.Middleware(cfg.Auth, cfg.APIKeys, PublicRoutes...))
+	}
+
+	// Rate limiting runs after authentication so clients are told apart by
+	// identity rather than by address where possible.
+	if cfg.RateLimitStore != nil {
+		r.Use(ratelimit.Middleware(cfg.RateLimitStore, cfg.RateLimits))
+	}
+
+	if cfg.IdempotencyStore != nil {
+		r.Use(idempotency.Middleware(cfg.IdempotencyStore, cfg.IdempotencyTTL))
+	}
+
+	return r
+}
+
+// RouteNames returns the names of every route New registers.
+func RouteNames() []string {
+	var names []string
+	New(Config{}).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		names = append(names, route.GetName())
+		return nil
+	})
+	return names
+}
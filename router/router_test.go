@@ -0,0 +1,368 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"api-productnorder/alerts"
+	"api-productnorder/auth"
+	"api-productnorder/currency"
+	"api-productnorder/health"
+	"api-productnorder/models"
+	"api-productnorder/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+var testSecret = []byte("router-test-secret")
+
+func signToken(t *testing.T, roles []string, customerID int64) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"sub":   "user",
+		"roles": roles,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	if customerID != 0 {
+		claims["customer_id"] = customerID
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// TestRouteAuthorization calls every route as every role and checks that the
+// caller is either turned away (401 without a token, 403 without permission)
+// or let through to the handler. The store holds customers 7 and 8 and an
+// order for each, so that checks on who owns a record see real records; only
+// the access decision is under test.
+func TestRouteAuthorization(t *testing.T) {
+	validator, err := auth.NewValidator(auth.Config{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(Config{Store: seedOwnedOrders(t), Auth: validator})
+
+	const (
+		anonymous = "anonymous"
+		customer  = "customer"
+		staff     = "staff"
+		admin     = "admin"
+	)
+	tokens := map[string]string{
+		anonymous: "",
+		customer:  signToken(t, []string{"customer"}, 7),
+		staff:     signToken(t, []string{"staff"}, 0),
+		admin:     signToken(t, []string{"admin"}, 0),
+	}
+
+	everyone := []string{anonymous, customer, staff, admin}
+	signedIn := []string{customer, staff, admin}
+	staffAndAdmin := []string{staff, admin}
+	adminOnly := []string{admin}
+
+	tests := []struct {
+		route   string
+		method  string
+		path    string
+		allowed []string
+	}{
+		{"health.live", "GET", "/healthz", everyone},
+		{"health.ready", "GET", "/readyz", everyone},
+		{"docs.spec", "GET", "/openapi.json", everyone},
+		{"docs.ui", "GET", "/docs", everyone},
+		{"docs.assets", "GET", "/docs/swagger-ui.css", everyone},
+
+		{"products.list", "GET", "/api/products", everyone},
+		{"products.create", "POST", "/api/products", staffAndAdmin},
+		{"products.low-stock", "GET", "/api/products/low-stock", staffAndAdmin},
+		{"products.detail", "GET", "/api/products/1", everyone},
+		{"products.update", "PUT", "/api/products/1", staffAndAdmin},
+		{"products.delete", "DELETE", "/api/products/1", adminOnly},
+		{"products.adjust-stock", "POST", "/api/products/1/stock-adjustments", staffAndAdmin},
+
+		{"warehouses.list", "GET", "/api/warehouses", staffAndAdmin},
+		{"warehouses.create", "POST", "/api/warehouses", staffAndAdmin},
+		{"warehouses.detail", "GET", "/api/warehouses/1", staffAndAdmin},
+		{"warehouses.update", "PUT", "/api/warehouses/1", staffAndAdmin},
+		{"warehouses.delete", "DELETE", "/api/warehouses/1", adminOnly},
+
+		{"customers.list", "GET", "/api/customers", staffAndAdmin},
+		{"customers.create", "POST", "/api/customers", staffAndAdmin},
+		{"customers.detail", "GET", "/api/customers/7", signedIn},
+		{"customers.detail", "GET", "/api/customers/8", staffAndAdmin},
+		{"customers.update", "PUT", "/api/customers/7", staffAndAdmin},
+		{"customers.delete", "DELETE", "/api/customers/1", adminOnly},
+		{"customers.orders", "GET", "/api/customers/7/orders", signedIn},
+		{"customers.orders", "GET", "/api/customers/8/orders", staffAndAdmin},
+
+		{"orders.list", "GET", "/api/orders", signedIn},
+		{"orders.create", "POST", "/api/orders", signedIn},
+		{"orders.detail", "GET", "/api/orders/2", signedIn},
+		{"orders.detail", "GET", "/api/orders/1", staffAndAdmin},
+		{"orders.delete", "DELETE", "/api/orders/1", adminOnly},
+		{"orders.update-items", "PUT", "/api/orders/1/items", staffAndAdmin},
+		{"orders.update-addresses", "PUT", "/api/orders/1/addresses", staffAndAdmin},
+
+		{"api-keys.list", "GET", "/api/admin/api-keys", adminOnly},
+		{"api-keys.create", "POST", "/api/admin/api-keys", adminOnly},
+		{"api-keys.revoke", "DELETE", "/api/admin/api-keys/1", adminOnly},
+
+		{"exchange-rates.list", "GET", "/api/exchange-rates", staffAndAdmin},
+		{"exchange-rates.update", "PUT", "/api/admin/exchange-rates/USD", adminOnly},
+		{"reports.sales", "GET", "/api/reports/sales", staffAndAdmin},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		covered[tt.route] = true
+		for _, role := range everyone {
+			allowed := false
+			for _, a := range tt.allowed {
+				if a == role {
+					allowed = true
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			if tokens[role] != "" {
+				req.Header.Set("Authorization", "Bearer "+tokens[role])
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			switch {
+			case allowed && (rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden):
+				t.Errorf("%s %s as %s: got %d, want access", tt.method, tt.path, role, rec.Code)
+			case !allowed && role == anonymous && rec.Code != http.StatusUnauthorized:
+				t.Errorf("%s %s as %s: got %d, want 401", tt.method, tt.path, role, rec.Code)
+			case !allowed && role != anonymous && rec.Code != http.StatusForbidden:
+				t.Errorf("%s %s as %s: got %d, want 403", tt.method, tt.path, role, rec.Code)
+			}
+		}
+	}
+
+	err = r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if name := route.GetName(); !covered[name] {
+			t.Errorf("route %q has no authorization test", name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedOwnedOrders returns a store holding customers 1 to 8, order 1 placed by
+// customer 8 and order 2 placed by customer 7.
+func seedOwnedOrders(t *testing.T) repository.Store {
+	t.Helper()
+	store := repository.NewMemory()
+	for i := 1; i <= 8; i++ {
+		if _, err := store.CreateCustomer(models.Customer{Name: "Customer", Email: fmt.Sprintf("customer%d@example.com", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	product, err := store.CreateProduct("Kopi", 25000, currency.Default, nil, 10, 0, models.StockPolicyReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, customerID := range []int64{8, 7} {
+		order := repository.NewOrder{CustomerID: customerID, Items: []repository.OrderItem{{ProductID: product.ID, Quantity: 1}}}
+		if _, _, err := store.CreateOrder(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestCustomerCannotOrderForSomeoneElse(t *testing.T) {
+	validator, err := auth.NewValidator(auth.Config{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(Config{Auth: validator})
+
+	body := `{"customer_id": 8, "products": [{"id": 1, "quantity": 1}]}`
+	req := httptest.NewRequest("POST", "/api/orders", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+signToken(t, []string{"customer"}, 7))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403", rec.Code)
+	}
+}
+
+func TestCustomerRoleWithoutCustomerIDIsForbidden(t *testing.T) {
+	validator, err := auth.NewValidator(auth.Config{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(Config{Auth: validator})
+
+	req := httptest.NewRequest("GET", "/api/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, []string{"customer"}, 0))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403", rec.Code)
+	}
+}
+
+// fakeKeyStore accepts a fixed set of API keys with the given scopes.
+type fakeKeyStore map[string][]string
+
+func (f fakeKeyStore) LookupAPIKey(hash string) (*auth.Principal, error) {
+	for key, scopes := range f {
+		if auth.HashAPIKey(key) == hash {
+			return &auth.Principal{Subject: "api-key:1", APIKeyID: 1, Scopes: scopes}, nil
+		}
+	}
+	return nil, auth.ErrInvalidAPIKey
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	keys := fakeKeyStore{
+		"scanner": {"products:read", "products:write"},
+		"erp":     {"orders:write"},
+	}
+	r := New(Config{APIKeys: keys})
+
+	tests := []struct {
+		key    string
+		method string
+		path   string
+		want   int
+	}{
+		{"scanner", "GET", "/api/products/low-stock", 0},
+		{"scanner", "POST", "/api/products/1/stock-adjustments", 0},
+		{"scanner", "DELETE", "/api/products/1", http.StatusForbidden},
+		{"scanner", "POST", "/api/orders", http.StatusForbidden},
+		{"erp", "POST", "/api/orders", 0},
+		{"erp", "GET", "/api/orders", http.StatusForbidden},
+		{"erp", "GET", "/api/admin/api-keys", http.StatusForbidden},
+		{"unknown", "GET", "/api/products", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+		req.Header.Set(auth.APIKeyHeader, tt.key)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if tt.want == 0 {
+			if rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden {
+				t.Errorf("%s %s with %s key: got %d, want access", tt.method, tt.path, tt.key, rec.Code)
+			}
+			continue
+		}
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %s key: got %d, want %d", tt.method, tt.path, tt.key, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	checker := health.New()
+	var dbErr error
+	checker.Add("database", func(context.Context) error { return dbErr })
+	r := New(Config{Health: checker})
+
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+		return rec.Code
+	}
+
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("healthy: got %d, want 200", code)
+	}
+
+	dbErr = errors.New("connection refused")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"database":{"status":"fail"}`) {
+		t.Fatalf("database down: got %d %s, want 503 with the database failing", rec.Code, rec.Body.String())
+	}
+	// The probe is public, so the error itself stays in the server log.
+	if strings.Contains(rec.Body.String(), "connection refused") {
+		t.Fatalf("database down: got %s, want the error left out", rec.Body.String())
+	}
+
+	dbErr = nil
+	checker.SetDraining()
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("draining: got %d, want 503", code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("liveness while draining: got %d, want 200", rec.Code)
+	}
+}
+
+// recordingSink collects the low-stock alerts dispatched to it.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []alerts.LowStockEvent
+}
+
+func (s *recordingSink) Emit(ctx context.Context, event alerts.LowStockEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// TestLowStockAlertsUntilRestock checks that a product crossing below its
+// reorder threshold raises one alert, that further orders raise none until
+// it is restocked, and that the next shortage after a restock alerts again.
+func TestLowStockAlertsUntilRestock(t *testing.T) {
+	sink := &recordingSink{}
+	alerts.SetSink(sink)
+	t.Cleanup(func() { alerts.SetSink(alerts.LogSink{}) })
+	r := New(Config{})
+
+	steps := []struct {
+		step   e2eStep
+		alerts []int64
+	}{
+		{e2eStep{method: "POST", path: "/api/products", body: `{"name": "Kopi", "price": 25000, "stock": 5, "reorder_threshold": 3}`, status: http.StatusCreated}, nil},
+		{e2eStep{method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 2}]}`, status: http.StatusOK}, nil},
+		{e2eStep{method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}]}`, status: http.StatusOK}, []int64{2}},
+		{e2eStep{method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 1}]}`, status: http.StatusOK}, []int64{2}},
+		{e2eStep{method: "POST", path: "/api/products/1/stock-adjustments", body: `{"delta": 10, "reason": "restock"}`, status: http.StatusCreated}, []int64{2}},
+		{e2eStep{method: "POST", path: "/api/orders", body: `{"products": [{"id": 1, "quantity": 9}]}`, status: http.StatusOK}, []int64{2, 2}},
+		{e2eStep{method: "PUT", path: "/api/orders/4/items", body: `{"products": [{"id": 1, "quantity": 10}]}`, status: http.StatusOK}, []int64{2, 2}},
+	}
+
+	for i, tt := range steps {
+		tt.step.run(t, r)
+		if err := alerts.Drain(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		sink.mu.Lock()
+		var stocks []int64
+		for _, event := range sink.events {
+			stocks = append(stocks, event.Stock)
+		}
+		sink.mu.Unlock()
+		if fmt.Sprint(stocks) != fmt.Sprint(tt.alerts) {
+			t.Errorf("step %d (%s %s): alerts at stock %v, want %v", i, tt.step.method, tt.step.path, stocks, tt.alerts)
+		}
+	}
+}
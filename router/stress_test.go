@@ -0,0 +1,181 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"api-productnorder/dbtest"
+	"api-productnorder/models"
+	"api-productnorder/repository"
+)
+
+// stressStores returns every store the stress test runs against: memory and
+// the SQL store on every dialect dbtest can open. PostgreSQL and MySQL run
+// only when POSTGRES_TEST_URL and MYSQL_TEST_URL name throwaway databases.
+func stressStores() map[string]func(t *testing.T) repository.Store {
+	stores := map[string]func(t *testing.T) repository.Store{
+		"memory": func(t *testing.T) repository.Store {
+			return repository.NewMemory()
+		},
+	}
+	for _, dialect := range dbtest.Dialects {
+		dialect := dialect
+		stores[dialect] = func(t *testing.T) repository.Store {
+			return repository.NewSQL(dbtest.Open(t, dialect), repository.Dialect(dialect))
+		}
+	}
+	return stores
+}
+
+// TestConcurrentOrdersDoNotOversell fires hundreds of orders for one product
+// at the server at once, while a watcher polls the product, and checks that
+// stock never goes negative, that stock + sold never changes, and that every
+// order the server accepted was stored with the quantity asked for.
+func TestConcurrentOrdersDoNotOversell(t *testing.T) {
+	for name, open := range stressStores() {
+		open := open
+		t.Run(name, func(t *testing.T) {
+			const initialStock, buyers = 100, 300
+			server := httptest.NewServer(New(Config{Store: open(t)}))
+			defer server.Close()
+
+			var created productResponse
+			status := stressRequest(t, "POST", server.URL+"/api/products", fmt.Sprintf(`{"name": "Widget", "price": 1000, "stock": %d}`, initialStock), &created)
+			if status != http.StatusCreated {
+				t.Fatalf("creating product: got %d", status)
+			}
+			productURL := fmt.Sprintf("%s/api/products/%d", server.URL, created.Data.ID)
+
+			// The watcher checks the invariants while orders are in flight,
+			// not only once they have settled.
+			done := make(chan struct{})
+			watched := make(chan error, 1)
+			go func() {
+				var err error
+				for err == nil {
+					select {
+					case <-done:
+						watched <- nil
+						return
+					default:
+					}
+					var product productResponse
+					if status := stressRequest(t, "GET", productURL, "", &product); status != http.StatusOK {
+						err = fmt.Errorf("reading product: got %d", status)
+					} else if product.Data.Stock < 0 || product.Data.Stock+product.Data.Sold != initialStock {
+						err = fmt.Errorf("mid-run stock %d, sold %d", product.Data.Stock, product.Data.Sold)
+					}
+				}
+				watched <- err
+			}()
+
+			type result struct {
+				quantity int64
+				status   int
+				order    models.LegacyDetailOrder
+			}
+			results := make([]result, buyers)
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					quantity := int64(i%3 + 1)
+					body := fmt.Sprintf(`{"products": [{"id": %d, "quantity": %d}]}`, created.Data.ID, quantity)
+					<-start
+					results[i].quantity = quantity
+					results[i].status = stressRequest(t, "POST", server.URL+"/api/orders", body, &results[i].order)
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+			close(done)
+			if err := <-watched; err != nil {
+				t.Error(err)
+			}
+
+			var accepted, sold int64
+			for _, result := range results {
+				switch result.status {
+				case http.StatusOK:
+					accepted++
+					sold += result.quantity
+				case http.StatusBadRequest:
+					// Out of stock.
+				default:
+					t.Errorf("order for %d: got %d", result.quantity, result.status)
+				}
+			}
+			if accepted == 0 || accepted == buyers {
+				t.Errorf("%d of %d orders accepted, want some turned away", accepted, buyers)
+			}
+
+			var product productResponse
+			stressRequest(t, "GET", productURL, "", &product)
+			if product.Data.Stock < 0 {
+				t.Errorf("stock went negative: %d", product.Data.Stock)
+			}
+			if product.Data.Stock+product.Data.Sold != initialStock {
+				t.Errorf("stock %d + sold %d, want %d", product.Data.Stock, product.Data.Sold, initialStock)
+			}
+			if product.Data.Sold != sold {
+				t.Errorf("sold %d, accepted orders add up to %d", product.Data.Sold, sold)
+			}
+
+			var orders models.LegacyListOrder
+			stressRequest(t, "GET", server.URL+"/api/orders", "", &orders)
+			if int64(len(orders.Data)) != accepted {
+				t.Errorf("%d orders stored, %d accepted", len(orders.Data), accepted)
+			}
+			for _, result := range results {
+				if result.status != http.StatusOK {
+					continue
+				}
+				var order models.LegacyDetailOrder
+				if status := stressRequest(t, "GET", fmt.Sprintf("%s/api/orders/%d", server.URL, *result.order.Data.ID), "", &order); status != http.StatusOK {
+					t.Errorf("order %d: got %d", *result.order.Data.ID, status)
+					continue
+				}
+				if len(order.Data.Products) != 1 || order.Data.Products[0].Quantity != result.quantity {
+					t.Errorf("order %d stored %+v, want quantity %d", *result.order.Data.ID, order.Data.Products, result.quantity)
+				}
+			}
+		})
+	}
+}
+
+// stressRequest sends a JSON request and decodes a successful response into
+// out. It is safe to call from several goroutines.
+func stressRequest(t *testing.T, method, url, body string, out interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	if resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Errorf("%s %s: decoding response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}